@@ -0,0 +1,386 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// The binary encoding of Values is a type-tagged, length-prefixed format.
+// A stream starts with a header made of binaryMagic followed by the format
+// version. It is then a sequence of encoded Values.
+//
+// Each Value starts with a tag byte:
+//
+//	tagNil, tagNull, tagFalse, tagTrue: no payload
+//	tagString, tagBytes:                uvarint length, data
+//	tagNumber:                          8 bytes, big-endian IEEE 754
+//	tagInt:                             zig-zag varint
+//	tagTime:                            uvarint length, time.Time.MarshalBinary data
+//	tagList:                            uvarint count, Values
+//	tagObject:                          typ as uvarint length and data, uvarint count,
+//	                                    then for each entry the key (uvarint length, data)
+//	                                    and the Value
+//	tagRaw:                             uvarint length, JSON data (non-Value Object entries)
+//
+// Elements and application-defined Values are encoded as objects built from
+// their raw representation.
+const (
+	tagNil byte = iota
+	tagNull
+	tagFalse
+	tagTrue
+	tagString
+	tagNumber
+	tagInt
+	tagBytes
+	tagTime
+	tagList
+	tagObject
+	tagRaw
+)
+
+// BinaryCodecVersion is the version of the binary format written by a
+// ValueEncoder.
+const BinaryCodecVersion = 1
+
+var binaryMagic = []byte("PUIV")
+
+var ErrInvalidBinaryValue = errors.New("invalid binary encoded Value")
+
+// ValueEncoder writes the binary encoding of Values to an output stream.
+// It is typically used to persist Element properties or to ship Commands and
+// MutationRecords over a wire in a more compact way than MarshalValue does.
+type ValueEncoder struct {
+	w           io.Writer
+	buf         *bytes.Buffer
+	wroteHeader bool
+}
+
+// NewValueEncoder returns a new encoder writing to w.
+func NewValueEncoder(w io.Writer) *ValueEncoder {
+	return &ValueEncoder{w, new(bytes.Buffer), false}
+}
+
+// Encode writes the binary encoding of v to the stream.
+// The stream header is written before the first Value.
+func (e *ValueEncoder) Encode(v Value) error {
+	if err := checkRegistered(v); err != nil {
+		return err
+	}
+	e.buf.Reset()
+	if !e.wroteHeader {
+		e.buf.Write(binaryMagic)
+		e.buf.WriteByte(BinaryCodecVersion)
+	}
+	if err := encodeBinary(e.buf, v); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(e.buf.Bytes()); err != nil {
+		return err
+	}
+	e.wroteHeader = true
+	return nil
+}
+
+// ValueDecoder reads binary encoded Values from an input stream.
+type ValueDecoder struct {
+	r          *bufio.Reader
+	readHeader bool
+}
+
+// NewValueDecoder returns a new decoder reading from r.
+func NewValueDecoder(r io.Reader) *ValueDecoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &ValueDecoder{br, false}
+}
+
+// Decode reads the next Value from the stream.
+// It returns io.EOF once the stream has been entirely consumed.
+func (d *ValueDecoder) Decode() (Value, error) {
+	if !d.readHeader {
+		header := make([]byte, len(binaryMagic)+1)
+		if _, err := io.ReadFull(d.r, header); err != nil {
+			if err == io.EOF {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: unable to read stream header: %v", ErrInvalidBinaryValue, err)
+		}
+		if !bytes.Equal(header[:len(binaryMagic)], binaryMagic) {
+			return nil, fmt.Errorf("%w: bad stream header", ErrInvalidBinaryValue)
+		}
+		if v := int(header[len(binaryMagic)]); v < 1 || v > BinaryCodecVersion {
+			return nil, fmt.Errorf("%w: got %d, expected at most %d", ErrUnsupportedCodecVersion, v, BinaryCodecVersion)
+		}
+		d.readHeader = true
+	}
+	if _, err := d.r.Peek(1); err == io.EOF {
+		return nil, io.EOF
+	}
+	return d.decode("")
+}
+
+// MarshalBinaryValue returns the binary encoding of a single Value, stream
+// header included.
+func MarshalBinaryValue(v Value) ([]byte, error) {
+	var b bytes.Buffer
+	if err := NewValueEncoder(&b).Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// UnmarshalBinaryValue decodes a Value encoded by MarshalBinaryValue.
+func UnmarshalBinaryValue(b []byte) (Value, error) {
+	v, err := NewValueDecoder(bytes.NewReader(b)).Decode()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: empty input", ErrInvalidBinaryValue)
+	}
+	return v, err
+}
+
+func encodeBinary(buf *bytes.Buffer, v Value) error {
+	switch t := v.(type) {
+	case nil:
+		buf.WriteByte(tagNil)
+	case NullValue:
+		buf.WriteByte(tagNull)
+	case Bool:
+		if t {
+			buf.WriteByte(tagTrue)
+		} else {
+			buf.WriteByte(tagFalse)
+		}
+	case String:
+		buf.WriteByte(tagString)
+		writeBinaryString(buf, string(t))
+	case Number:
+		buf.WriteByte(tagNumber)
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(float64(t)))
+		buf.Write(b[:])
+	case Int:
+		buf.WriteByte(tagInt)
+		var b [binary.MaxVarintLen64]byte
+		buf.Write(b[:binary.PutVarint(b[:], int64(t))])
+	case Bytes:
+		buf.WriteByte(tagBytes)
+		writeBinaryString(buf, string(t))
+	case Time:
+		data, err := time.Time(t).MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBinaryValue, err)
+		}
+		buf.WriteByte(tagTime)
+		writeBinaryString(buf, string(data))
+	case List:
+		buf.WriteByte(tagList)
+		writeUvarint(buf, uint64(len(t)))
+		for _, item := range t {
+			if err := encodeBinary(buf, item); err != nil {
+				return err
+			}
+		}
+	case Object:
+		return encodeBinaryObject(buf, t.ValueType(), t)
+	case Command:
+		return encodeBinaryObject(buf, t.ValueType(), Object(t))
+	case MutationRecord:
+		return encodeBinaryObject(buf, t.ValueType(), Object(t))
+	case ImmutableObject:
+		return encodeBinaryObject(buf, t.ValueType(), t.Object())
+	case ImmutableList:
+		o := NewObject()
+		o.Set("value", t.List())
+		return encodeBinaryObject(buf, t.ValueType(), o)
+	default:
+		raw := v.RawValue()
+		if raw == nil {
+			return fmt.Errorf("%w: %s Value has no raw representation", ErrInvalidRawValue, v.ValueType())
+		}
+		p, err := decodeFields(raw, "")
+		if err != nil {
+			return err
+		}
+		return encodeBinaryObject(buf, raw.ValueType(), p)
+	}
+	return nil
+}
+
+func encodeBinaryObject(buf *bytes.Buffer, typ string, o Object) error {
+	buf.WriteByte(tagObject)
+	writeBinaryString(buf, typ)
+	keys := sortedKeys(o) // deterministic output
+	count := len(keys)
+	if _, ok := o["typ"]; ok {
+		count--
+	}
+	writeUvarint(buf, uint64(count))
+	for _, k := range keys {
+		if k == "typ" {
+			continue
+		}
+		writeBinaryString(buf, k)
+		switch val := o[k].(type) {
+		case nil:
+			buf.WriteByte(tagNil)
+		case Value:
+			if err := encodeBinary(buf, val); err != nil {
+				return err
+			}
+		default:
+			data, err := json.Marshal(val)
+			if err != nil {
+				return fmt.Errorf("%w: entry %q: %v", ErrInvalidBinaryValue, k, err)
+			}
+			buf.WriteByte(tagRaw)
+			writeBinaryString(buf, string(data))
+		}
+	}
+	return nil
+}
+
+func writeUvarint(buf *bytes.Buffer, n uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], n)])
+}
+
+func writeBinaryString(buf *bytes.Buffer, s string) {
+	writeUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func (d *ValueDecoder) decode(path string) (Value, error) {
+	v, _, err := d.decodeEntry(path)
+	return v, err
+}
+
+// decodeEntry decodes the next item of the stream. If it is a non-Value Object
+// entry, it is returned as raw.
+func (d *ValueDecoder) decodeEntry(path string) (v Value, raw interface{}, err error) {
+	tag, err := d.r.ReadByte()
+	if err != nil {
+		return nil, nil, d.unexpected(err, path)
+	}
+	switch tag {
+	case tagNil:
+		return nil, nil, nil
+	case tagNull:
+		return Null, nil, nil
+	case tagFalse:
+		return Bool(false), nil, nil
+	case tagTrue:
+		return Bool(true), nil, nil
+	case tagString:
+		s, err := d.readString(path)
+		return String(s), nil, err
+	case tagNumber:
+		var b [8]byte
+		if _, err := io.ReadFull(d.r, b[:]); err != nil {
+			return nil, nil, d.unexpected(err, path)
+		}
+		return Number(math.Float64frombits(binary.BigEndian.Uint64(b[:]))), nil, nil
+	case tagInt:
+		i, err := binary.ReadVarint(d.r)
+		if err != nil {
+			return nil, nil, d.unexpected(err, path)
+		}
+		return Int(i), nil, nil
+	case tagBytes:
+		s, err := d.readString(path)
+		return Bytes(s), nil, err
+	case tagTime:
+		s, err := d.readString(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		var t time.Time
+		if err := t.UnmarshalBinary([]byte(s)); err != nil {
+			return nil, nil, fmt.Errorf("%w: Time at %q: %v", ErrInvalidBinaryValue, path, err)
+		}
+		return Time(t), nil, nil
+	case tagList:
+		n, err := binary.ReadUvarint(d.r)
+		if err != nil {
+			return nil, nil, d.unexpected(err, path)
+		}
+		l := NewList()
+		for i := uint64(0); i < n; i++ {
+			item, err := d.decode(fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, nil, err
+			}
+			l = append(l, item)
+		}
+		return l, nil, nil
+	case tagObject:
+		typ, err := d.readString(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		n, err := binary.ReadUvarint(d.r)
+		if err != nil {
+			return nil, nil, d.unexpected(err, path)
+		}
+		p := NewObject().SetType(typ)
+		for i := uint64(0); i < n; i++ {
+			k, err := d.readString(path)
+			if err != nil {
+				return nil, nil, err
+			}
+			val, raw, err := d.decodeEntry(path + "." + k)
+			if err != nil {
+				return nil, nil, err
+			}
+			if raw != nil {
+				p[k] = raw
+				continue
+			}
+			p[k] = val
+		}
+		v, err := objectValue(typ, p, path)
+		return v, nil, err
+	case tagRaw:
+		s, err := d.readString(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := json.Unmarshal([]byte(s), &raw); err != nil {
+			return nil, nil, fmt.Errorf("%w: raw entry at %q: %v", ErrInvalidBinaryValue, path, err)
+		}
+		return nil, raw, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown tag %d at %q", ErrInvalidBinaryValue, tag, path)
+	}
+}
+
+func (d *ValueDecoder) readString(path string) (string, error) {
+	n, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return "", d.unexpected(err, path)
+	}
+	// The buffer grows with the data actually read so that a corrupted length
+	// cannot trigger a huge allocation.
+	var b bytes.Buffer
+	if _, err := io.CopyN(&b, d.r, int64(n)); err != nil {
+		return "", d.unexpected(err, path)
+	}
+	return b.String(), nil
+}
+
+func (d *ValueDecoder) unexpected(err error, path string) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("%w: at %q: %v", ErrInvalidBinaryValue, path, err)
+}
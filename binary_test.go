@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"testing"
+	"time"
+)
+
+// binaryValues returns a sample of every Value type supported by the binary
+// codec.
+func binaryValues(storeid string) map[string]Value {
+	values := builtinValues(storeid)
+	values["Int"] = Int(-9007199254740993) // not representable as a float64
+	values["Bytes"] = Bytes{0, 1, 255}
+	values["Time"] = Time(time.Date(2021, 3, 4, 5, 6, 7, 8, time.UTC))
+	values["Null"] = Null
+	values["ListWithNull"] = NewList(String("a"), Null, Int(2))
+	return values
+}
+
+func TestMarshalBinaryValueRoundTrip(t *testing.T) {
+	values := binaryValues("binarystore")
+	values["ListWithNil"] = NewList(String("a"), nil)
+	for name, v := range values {
+		b, err := MarshalBinaryValue(v)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		r, err := UnmarshalBinaryValue(b)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !Equal(r, v) {
+			t.Errorf("%s: got %#v, want %#v", name, r, v)
+		}
+	}
+}
+
+func TestValueEncoderStream(t *testing.T) {
+	values := binaryValues("streamstore")
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	enc := NewValueEncoder(&buf)
+	for _, name := range names {
+		if err := enc.Encode(values[name]); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	dec := NewValueDecoder(&buf)
+	for _, name := range names {
+		r, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !Equal(r, values[name]) {
+			t.Errorf("%s: got %#v, want %#v", name, r, values[name])
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("got %v, want io.EOF", err)
+	}
+}
+
+func TestUnmarshalBinaryValueTruncated(t *testing.T) {
+	b, err := MarshalBinaryValue(NewList(String("abc"), Int(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(b); i++ {
+		if _, err := UnmarshalBinaryValue(b[:i]); !errors.Is(err, ErrInvalidBinaryValue) {
+			t.Errorf("truncated at %d: got %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+var (
+	ErrNotCloneable = errors.New("element cannot be cloned")
+)
+
+// Clone returns a deep copy of the Element and of its subtree, views included.
+// Each Element is recreated by the constructor it was created with, with the
+// same constructor options, under the ID returned by newid for the ID of the
+// original. The copies are registered in the same ElementStore.
+// Property values are copied per category, preserving their group (Default,
+// Local, Inheritable). Lifecycle properties ("event" category) and consumed
+// context values are not copied: the copy is a detached subtree which consumes
+// the same context keys once attached. Neither are the last Command and the
+// mutation records, since setting them would replay the mutations of the
+// original on the copy. Computed properties are copied as plain values.
+//
+// If the optional flag is true, the handlers registered on the Elements of the
+// subtree via Watch, WatchPattern, AddEventListener and Intercept are registered
+// on their copies as well, on behalf of the copy of the observer when it belongs
+// to the subtree. Note that handlers are shared, not copied: a handler referring
+// to an original Element keeps referring to it.
+func (e *Element) Clone(newid func(id string) string, flags ...bool) (*Element, error) {
+	var rewatch bool
+	if len(flags) > 0 {
+		rewatch = flags[0]
+	}
+	ids := make(map[*Element]string)
+	taken := make(map[string]bool)
+	var err error
+	e.Walk(func(el *Element) bool {
+		err = cloneable(el)
+		if err != nil {
+			return false
+		}
+		id := newid(el.ID)
+		if _, ok := el.ElementStore.ByID[id]; ok || taken[id] {
+			err = fmt.Errorf("%w: Element %s: ID %s is already in use", ErrNotCloneable, el.ID, id)
+			return false
+		}
+		taken[id] = true
+		ids[el] = id
+		return true
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	clones := make(map[*Element]*Element)
+	c := clone(e, ids, clones)
+	if rewatch {
+		done := make(map[*Subscription]bool)
+		for original := range clones {
+			for s := range original.subscriptions {
+				if done[s] {
+					continue
+				}
+				done[s] = true
+				resubscribe(s, clones)
+			}
+		}
+	}
+	return c, nil
+}
+
+// cloneable returns an error if the Element was not created by a constructor
+// registered in its ElementStore.
+func cloneable(e *Element) error {
+	if e.ElementStore == nil {
+		return fmt.Errorf("%w: Element %s does not belong to an ElementStore", ErrNotCloneable, e.ID)
+	}
+	v, ok := e.Get("internals", "constructor")
+	if !ok {
+		return fmt.Errorf("%w: Element %s was not created by a constructor", ErrNotCloneable, e.ID)
+	}
+	name, ok := v.(String)
+	if !ok {
+		return fmt.Errorf("%w: Element %s: invalid constructor name", ErrNotCloneable, e.ID)
+	}
+	if _, ok := e.ElementStore.Constructors[string(name)]; !ok {
+		return fmt.Errorf("%w: Element %s: constructor %s is not registered", ErrNotCloneable, e.ID, name)
+	}
+	return nil
+}
+
+// clone recreates e and its subtree. clones maps the originals to their copies.
+func clone(e *Element, ids map[*Element]string, clones map[*Element]*Element) *Element {
+	v, _ := e.Get("internals", "constructor")
+	var options []string
+	if o, ok := e.Get("internals", "constructoroptions"); ok {
+		if l, ok := o.(List); ok {
+			for _, opt := range l {
+				if s, ok := opt.(String); ok {
+					options = append(options, string(s))
+				}
+			}
+		}
+	}
+	c := e.ElementStore.Constructors[string(v.(String))](e.Name, ids[e], options...)
+	clones[e] = c
+	c.ActiveView = e.ActiveView
+
+	for category, ps := range e.Properties.Categories {
+		if category == "event" || category == "consumed" {
+			continue
+		}
+		for propname, value := range ps.Default {
+			c.Properties.SetDefault(category, propname, copyValue(value))
+		}
+		for propname, value := range ps.Local {
+			copyProperty(c, category, propname, value, false)
+		}
+		for propname, value := range ps.Inheritable {
+			copyProperty(c, category, propname, value, true)
+		}
+	}
+	for key := range e.contexts {
+		c.Consume(key)
+	}
+
+	for _, child := range e.Children.List {
+		cc := clone(child, ids, clones)
+		cc.ViewAccessNode = newViewAccessNode(c, e.ActiveView)
+		c.appendChild(cc)
+	}
+	if e.isViewElement() {
+		for name, view := range e.InactiveViews {
+			elements := make([]*Element, 0, len(view.Elements().List))
+			for _, child := range view.Elements().List {
+				elements = append(elements, clone(child, ids, clones))
+			}
+			c.addView(View{name, NewElements(elements...), view.Parameterize})
+		}
+		NewViewElement(c)
+	}
+	return c
+}
+
+// uncopied lists the properties which are not copied by Clone: setting them
+// triggers the replay of mutations by their handlers.
+var uncopied = map[string]bool{
+	"ui/command":         true,
+	"ui/mutationrecords": true,
+}
+
+// copyProperty sets a copy of a property value on the clone c, unless the
+// constructor of c already set it.
+func copyProperty(c *Element, category string, propname string, value Value, inheritable bool) {
+	if uncopied[category+"/"+propname] {
+		return
+	}
+	if cp, ok := c.Properties.Categories[category]; ok {
+		v, ok := cp.Local[propname]
+		if inheritable {
+			v, ok = cp.Inheritable[propname]
+		}
+		if ok && Equal(v, value) {
+			return
+		}
+	}
+	if err := c.Set(category, propname, copyValue(value), inheritable); err != nil {
+		log.Print(err)
+	}
+}
+
+// resubscribe registers the handler of a Subscription on behalf of the copies
+// of its subscriber and target.
+func resubscribe(s *Subscription, clones map[*Element]*Element) {
+	if !s.Active() || s.managed() {
+		return
+	}
+	target, ok := clones[s.target]
+	if !ok {
+		return
+	}
+	subscriber := s.subscriber
+	if c, ok := clones[subscriber]; ok {
+		subscriber = c
+	}
+
+	switch h := s.handler.(type) {
+	case *MutationHandler:
+		if registered(s.target.PropMutationHandlers.list, s.key, h) {
+			category, propname, ok := splitObservedKey(s.target.ID, s.key)
+			if !ok || registered(target.PropMutationHandlers.list, target.ID+"/"+category+"/"+propname, h) {
+				return
+			}
+			subscriber.Watch(category, propname, target, h)
+			return
+		}
+		if registered(s.target.PropMutationHandlers.patterns, s.key, h) {
+			if registered(target.PropMutationHandlers.patterns, s.key, h) {
+				return
+			}
+			if _, err := target.WatchPattern(s.key, h); err != nil {
+				log.Print(err)
+			}
+		}
+	case *EventHandler:
+		target.AddEventListener(s.key, h, s.bridge)
+	case *interceptor:
+		category, propname, ok := splitObservedKey(s.target.ID, s.key)
+		if !ok {
+			return
+		}
+		target.Intercept(category, propname, h.fn)
+	}
+}
+
+// managed reports whether the Subscription is maintained internally, on behalf
+// of a computed property or of a context consumer.
+func (s *Subscription) managed() bool {
+	for _, c := range s.subscriber.computed {
+		if s.handler == c.handler {
+			return true
+		}
+	}
+	for _, sub := range s.subscriber.contexts {
+		if sub == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+)
+
+func copyID(id string) string { return id + "-copy" }
+
+func TestClone(t *testing.T) {
+	s, thing := newTestStore("clonestore", AllowMutationDeduplication)
+	root := s.NewAppRoot("cloneroot")
+	a := thing("card", "ca", "mutationdeduplication")
+	b := thing("title", "cb")
+	a.AppendChild(b)
+	a.Set("data", "items", NewList(String("x")))
+	a.Set("ui", "theme", String("dark"), true)
+	a.Properties.SetDefault("ui", "size", Int(3))
+	b.Set("ui", "text", String("hello"))
+	root.AppendChild(a)
+	v := NewViewElement(thing("views", "cv"), NewView("one", thing("x", "cx")), NewView("two", thing("y", "cy")))
+	a.AppendChild(v.Element())
+	v.ActivateView("one")
+
+	c, err := a.Clone(copyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID != "ca-copy" || c.Parent != nil || c.Children.List[0].ID != "cb-copy" || s.GetByID("cy-copy") == nil {
+		t.Fatalf("wrong structure for clone %s", c.ID)
+	}
+	if o, _ := c.Get("internals", "constructoroptions"); !Equal(o, NewList(String("mutationdeduplication"))) {
+		t.Errorf("got constructor options %v", o)
+	}
+	if v, _ := c.Get("data", "items"); !Equal(v, NewList(String("x"))) {
+		t.Errorf("got %v, want [x]", v)
+	}
+	if g := c.Properties.Categories["ui"].PropertyGroup("theme"); g != "Inheritable" {
+		t.Errorf("got group %s, want Inheritable", g)
+	}
+	if g := c.Properties.Categories["ui"].PropertyGroup("size"); g != "Default" {
+		t.Errorf("got group %s, want Default", g)
+	}
+	if v, _ := c.Children.List[0].Get("ui", "text"); !Equal(v, String("hello")) {
+		t.Errorf("got %v, want hello", v)
+	}
+
+	cv := s.GetByID("cv-copy")
+	if cv.ActiveView != "one" || len(cv.InactiveViews) != 1 || cv.Children.List[0].ID != "cx-copy" {
+		t.Fatalf("wrong views: active %q, inactive %v", cv.ActiveView, cv.InactiveViews)
+	}
+	if err := (ViewElement{cv}).ActivateView("two"); err != nil || cv.Children.List[0].ID != "cy-copy" {
+		t.Errorf("could not activate the copy of an inactive view: %v", err)
+	}
+
+	if _, err := a.Clone(copyID); !errors.Is(err, ErrNotCloneable) {
+		t.Errorf("ID reuse: got %v, want ErrNotCloneable", err)
+	}
+	if _, err := NewElement("bare", "bare", "test").Clone(copyID); !errors.Is(err, ErrNotCloneable) {
+		t.Errorf("Element without constructor: got %v, want ErrNotCloneable", err)
+	}
+}
+
+func TestCloneLeavesOriginalUnchanged(t *testing.T) {
+	s, thing := newTestStore("cloneoriginal")
+	root := s.NewAppRoot("cloneoriginal-root")
+	a := thing("a", "oa")
+	b := thing("b", "ob")
+	root.AppendChild(a)
+	a.Mutate(AppendChildCommand(b))
+	a.SetData("x", Number(1))
+
+	c, err := a.Clone(copyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Children.List) != 1 || a.Children.List[0] != b || b.Parent != a {
+		t.Errorf("children of the original changed: %v", ids(a.Children))
+	}
+	if len(c.Children.List) != 1 || c.Children.List[0].ID != "ob-copy" {
+		t.Errorf("got children [%s] for the copy, want [ob-copy]", ids(c.Children))
+	}
+	if v, _ := a.GetData("x"); !Equal(v, Number(1)) {
+		t.Errorf("property of the original changed: got %v", v)
+	}
+	if a.Parent != root || c.Parent != nil {
+		t.Error("wrong parents")
+	}
+}
+
+func TestCloneIndependentChildren(t *testing.T) {
+	_, thing := newTestStore("cloneindependent")
+	a := thing("a", "ia")
+	b := thing("b", "ib")
+	a.AppendChild(b)
+	b.SetData("x", Number(1))
+
+	c, err := a.Clone(copyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := c.Children.List[0]
+	cb.SetData("x", Number(2))
+	if v, _ := b.GetData("x"); !Equal(v, Number(1)) {
+		t.Errorf("mutation of a copied child applied to the original: got %v", v)
+	}
+	c.AppendChild(thing("d", "id"))
+	c.removeChild(cb)
+	if len(a.Children.List) != 1 || a.Children.List[0] != b || b.Parent != a {
+		t.Errorf("children of the original changed: [%s]", ids(a.Children))
+	}
+	a.AppendChild(thing("e", "ie"))
+	if len(c.Children.List) != 1 || c.Children.List[0].ID != "id" {
+		t.Errorf("children of the copy changed: [%s]", ids(c.Children))
+	}
+}
+
+func TestCloneWatchers(t *testing.T) {
+	_, thing := newTestStore("clonewatchers")
+	a := thing("a", "wa")
+	b := thing("b", "wb")
+	a.AppendChild(b)
+	var hits []string
+	b.Watch("ui", "text", a, NewMutationHandler(func(evt MutationEvent) bool {
+		hits = append(hits, evt.Origin().ID)
+		return false
+	}))
+	a.Intercept("ui", "text", func(old Value, new Value) (Value, error) { return String("!"), nil })
+
+	c, err := a.Clone(copyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("ui", "text", String("a"))
+	if v, _ := c.Get("ui", "text"); !Equal(v, String("a")) || len(hits) != 0 {
+		t.Errorf("handlers copied without the flag: got %v and %v", v, hits)
+	}
+
+	c, err = a.Clone(func(id string) string { return id + "-watched" }, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("ui", "text", String("a"))
+	if v, _ := c.Get("ui", "text"); !Equal(v, String("!")) || len(hits) != 1 || hits[0] != "wa-watched" {
+		t.Errorf("handlers not copied with the flag: got %v and %v", v, hits)
+	}
+}
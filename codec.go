@@ -0,0 +1,429 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ValueCodecVersion is the version of the serialization format produced by
+// MarshalValue. It is embedded in every payload so that persisted data can be
+// told apart (and migrated) if the format ever evolves.
+const ValueCodecVersion = 1
+
+var (
+	ErrUnsupportedCodecVersion = errors.New("unsupported Value codec version")
+	ErrInvalidRawValue         = errors.New("invalid raw Value")
+	ErrValueTypeExists         = errors.New("Value type already registered")
+	ErrUnregisteredValueType   = errors.New("Value type not registered")
+)
+
+var builtinValueTypes = map[string]bool{
+	"Bool":            true,
+	"String":          true,
+	"Number":          true,
+	"Int":             true,
+	"Bytes":           true,
+	"Time":            true,
+	"Null":            true,
+	"List":            true,
+	"Object":          true,
+	"Command":         true,
+	"MutationRecord":  true,
+	"Element":         true,
+	"ImmutableObject": true,
+	"ImmutableList":   true,
+}
+
+// valueTypes holds the decoding functions of application-defined Value types,
+// indexed by the name returned by their ValueType method.
+var valueTypes = make(map[string]func(Object) (Value, error))
+var valueTypesMu sync.RWMutex // values may be decoded outside of the UI thread
+
+// RegisterValueType registers the decoding function for an application-defined
+// Value type. The function receives the raw Object produced by RawValue, in
+// which nested Values have already been decoded.
+// It allows custom types to survive persistence and Command payloads.
+// Built-in type names cannot be overridden.
+func RegisterValueType(typ string, decode func(raw Object) (Value, error)) error {
+	if builtinValueTypes[typ] {
+		return fmt.Errorf("%w: %q is a built-in type", ErrValueTypeExists, typ)
+	}
+	valueTypesMu.Lock()
+	defer valueTypesMu.Unlock()
+	if _, ok := valueTypes[typ]; ok {
+		return fmt.Errorf("%w: %q", ErrValueTypeExists, typ)
+	}
+	valueTypes[typ] = decode
+	return nil
+}
+
+// checkRegistered returns an error if v is, or holds, an application-defined
+// Value whose type has not been registered via RegisterValueType: it could be
+// encoded but not decoded back.
+func checkRegistered(v Value) error {
+	switch t := v.(type) {
+	case customValue:
+		valueTypesMu.RLock()
+		_, ok := valueTypes[t.ValueType()]
+		valueTypesMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrUnregisteredValueType, t.ValueType())
+		}
+	case List:
+		for _, item := range t {
+			if err := checkRegistered(item); err != nil {
+				return err
+			}
+		}
+	case Object:
+		return checkRegisteredEntries(t)
+	case Command:
+		return checkRegisteredEntries(Object(t))
+	case MutationRecord:
+		return checkRegisteredEntries(Object(t))
+	case ImmutableObject:
+		for _, field := range t.fields {
+			if err := checkRegistered(field); err != nil {
+				return err
+			}
+		}
+	case ImmutableList:
+		for _, item := range t.items {
+			if err := checkRegistered(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkRegisteredEntries(o Object) error {
+	for _, entry := range o {
+		if v, ok := entry.(Value); ok {
+			if err := checkRegistered(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type valueEnvelope struct {
+	Version *int                   `json:"version"`
+	Value   map[string]interface{} `json:"value"`
+}
+
+// MarshalValue returns the versioned JSON encoding of a Value.
+// The encoding is built from the RawValue representation of the Value so that
+// any Value, including nested Objects and Lists, can be persisted or sent over
+// the wire and then recovered with UnmarshalValue.
+// Application-defined Values can only be marshaled once their type has been
+// registered.
+func MarshalValue(v Value) ([]byte, error) {
+	if v == nil {
+		return nil, fmt.Errorf("%w: cannot marshal a nil Value", ErrInvalidRawValue)
+	}
+	if err := checkRegistered(v); err != nil {
+		return nil, err
+	}
+	raw := v.RawValue()
+	if raw == nil {
+		return nil, fmt.Errorf("%w: %s Value has no raw representation", ErrInvalidRawValue, v.ValueType())
+	}
+	version := ValueCodecVersion
+	return json.Marshal(valueEnvelope{&version, raw})
+}
+
+// UnmarshalValue decodes a payload produced by MarshalValue.
+// For every built-in Value type, UnmarshalValue(MarshalValue(v)) returns a Value
+// equal to v.
+func UnmarshalValue(b []byte) (Value, error) {
+	var env valueEnvelope
+	if err := json.Unmarshal(b, &env); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRawValue, err)
+	}
+	if env.Version == nil {
+		return nil, fmt.Errorf("%w: missing version field", ErrUnsupportedCodecVersion)
+	}
+	if *env.Version < 1 || *env.Version > ValueCodecVersion {
+		return nil, fmt.Errorf("%w: got %d, expected at most %d", ErrUnsupportedCodecVersion, *env.Version, ValueCodecVersion)
+	}
+	if env.Value == nil {
+		return nil, fmt.Errorf("%w: missing value field", ErrInvalidRawValue)
+	}
+	return DecodeValue(Object(env.Value))
+}
+
+// DecodeValue turns the raw representation of a Value, as returned by RawValue
+// (possibly after a round trip through encoding/json), back into a Value.
+// Unlike Object.Value, it reports why decoding failed.
+func DecodeValue(raw Object) (Value, error) {
+	return decodeObject(raw, "")
+}
+
+func decodeRaw(raw interface{}, path string) (Value, error) {
+	switch t := raw.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return decodeObject(Object(t), path)
+	case Object:
+		return decodeObject(t, path)
+	case Value:
+		return t, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected %T at %q", ErrInvalidRawValue, raw, path)
+	}
+}
+
+func decodeObject(o Object, path string) (Value, error) {
+	typ := o.ValueType()
+	switch typ {
+	case "Bool":
+		v, ok := o.Get("value")
+		if !ok {
+			return nil, missingField(typ, "value", path)
+		}
+		res, ok := v.(bool)
+		if !ok {
+			return nil, wrongField(typ, "value", v, path)
+		}
+		return Bool(res), nil
+	case "String":
+		v, ok := o.Get("value")
+		if !ok {
+			return nil, missingField(typ, "value", path)
+		}
+		res, ok := v.(string)
+		if !ok {
+			return nil, wrongField(typ, "value", v, path)
+		}
+		return String(res), nil
+	case "Number":
+		v, ok := o.Get("value")
+		if !ok {
+			return nil, missingField(typ, "value", path)
+		}
+		res, ok := v.(float64)
+		if !ok {
+			return nil, wrongField(typ, "value", v, path)
+		}
+		return Number(res), nil
+	case "Int":
+		v, ok := o.Get("value")
+		if !ok {
+			return nil, missingField(typ, "value", path)
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, wrongField(typ, "value", v, path)
+		}
+		res, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: Int at %q: %v", ErrInvalidRawValue, path, err)
+		}
+		return Int(res), nil
+	case "Bytes":
+		v, ok := o.Get("value")
+		if !ok {
+			return nil, missingField(typ, "value", path)
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, wrongField(typ, "value", v, path)
+		}
+		res, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: Bytes at %q: %v", ErrInvalidRawValue, path, err)
+		}
+		return Bytes(res), nil
+	case "Time":
+		v, ok := o.Get("value")
+		if !ok {
+			return nil, missingField(typ, "value", path)
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, wrongField(typ, "value", v, path)
+		}
+		res, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: Time at %q: %v", ErrInvalidRawValue, path, err)
+		}
+		return Time(res), nil
+	case "Null":
+		return Null, nil
+	case "List":
+		v, ok := o.Get("value")
+		if !ok {
+			return nil, missingField(typ, "value", path)
+		}
+		l, ok := v.([]interface{})
+		if !ok {
+			return nil, wrongField(typ, "value", v, path)
+		}
+		m := NewList()
+		for i, val := range l {
+			r, err := decodeRaw(val, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, r)
+		}
+		return m, nil
+	case "undefined", "undefined object":
+		return nil, fmt.Errorf("%w: missing or malformed typ field at %q", ErrInvalidRawValue, path)
+	default:
+		p, err := decodeFields(o, path)
+		if err != nil {
+			return nil, err
+		}
+		return objectValue(typ, p, path)
+	}
+}
+
+// objectValue returns the Value of type typ represented by an Object whose
+// fields have already been decoded.
+func objectValue(typ string, p Object, path string) (Value, error) {
+	switch typ {
+	case "Object":
+		return p, nil
+	case "Command":
+		return Command(p), nil
+	case "MutationRecord":
+		return MutationRecord(p), nil
+	case "Element":
+		return decodeElement(p, path)
+	case "ImmutableObject":
+		return NewImmutableObject(p), nil
+	case "ImmutableList":
+		v, ok := p.Get("value")
+		if !ok {
+			return nil, missingField(typ, "value", path)
+		}
+		switch l := v.(type) {
+		case List: // already decoded, e.g. by a ValueDecoder
+			return NewImmutableList(l...), nil
+		case []interface{}:
+			items := make([]Value, 0, len(l))
+			for i, val := range l {
+				r, err := decodeRaw(val, fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, r)
+			}
+			return NewImmutableList(items...), nil
+		default:
+			return nil, wrongField(typ, "value", v, path)
+		}
+	default:
+		valueTypesMu.RLock()
+		decode, ok := valueTypes[typ]
+		valueTypesMu.RUnlock()
+		if !ok {
+			return p, nil
+		}
+		v, err := decode(p)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to decode %s at %q: %v", ErrInvalidRawValue, typ, path, err)
+		}
+		return v, nil
+	}
+}
+
+func decodeFields(o Object, path string) (Object, error) {
+	p := Object(make(map[string]interface{}, len(o)))
+	for k, val := range o {
+		if k == "typ" {
+			p[k] = val
+			continue
+		}
+		switch val.(type) {
+		case map[string]interface{}, Object:
+			v, err := decodeRaw(val, path+"."+k)
+			if err != nil {
+				return nil, err
+			}
+			p[k] = v
+		default:
+			p[k] = val
+		}
+	}
+	return p, nil
+}
+
+func decodeElement(p Object, path string) (Value, error) {
+	id, err := stringField(p, "id", path)
+	if err != nil {
+		return nil, err
+	}
+	name, err := stringField(p, "name", path)
+	if err != nil {
+		return nil, err
+	}
+	storeid, err := stringField(p, "elementstoreid", path)
+	if err != nil {
+		return nil, err
+	}
+	cname, err := stringField(p, "constructorname", path)
+	if err != nil {
+		return nil, err
+	}
+
+	elstore, ok := Stores.Get(storeid)
+	if !ok {
+		return nil, fmt.Errorf("%w: ElementStore %q of Element %q does not exist", ErrInvalidRawValue, storeid, id)
+	}
+	// Let's try to see if the element is in the ElementStore already
+	if element := elstore.GetByID(id); element != nil {
+		return element, nil
+	}
+	// Otherwise we construct it.
+	constructor, ok := elstore.Constructors[cname]
+	if !ok {
+		return nil, fmt.Errorf("%w: constructor %q not found, cannot create Element %q", ErrInvalidRawValue, cname, id)
+	}
+
+	coptions := make([]string, 0)
+	if v, ok := p.Get("constructoroptions"); ok {
+		optlist, ok := v.(List)
+		if !ok {
+			return nil, wrongField("Element", "constructoroptions", v, path)
+		}
+		for _, opt := range optlist {
+			sopt, ok := opt.(String)
+			if !ok {
+				return nil, wrongField("Element", "constructoroptions", opt, path)
+			}
+			coptions = append(coptions, string(sopt))
+		}
+	}
+	return constructor(name, id, coptions...), nil
+}
+
+func stringField(p Object, field string, path string) (string, error) {
+	v, ok := p.Get(field)
+	if !ok {
+		return "", missingField(p.ValueType(), field, path)
+	}
+	s, ok := v.(String)
+	if !ok {
+		return "", wrongField(p.ValueType(), field, v, path)
+	}
+	return string(s), nil
+}
+
+func missingField(typ string, field string, path string) error {
+	return fmt.Errorf("%w: %s at %q is missing its %q field", ErrInvalidRawValue, typ, path, field)
+}
+
+func wrongField(typ string, field string, v interface{}, path string) error {
+	return fmt.Errorf("%w: %s at %q has a %q field of unexpected type %T", ErrInvalidRawValue, typ, path, field, v)
+}
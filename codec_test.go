@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// builtinValues returns a sample of every Value type of the original codec,
+// nested ones included.
+func builtinValues(storeid string) map[string]Value {
+	_, ctor := newTestStore(storeid)
+
+	obj := NewObject()
+	obj.Set("n", Number(3.5))
+	obj.Set("l", NewList(String("a"), Number(-2), NewList(Bool(true))))
+
+	return map[string]Value{
+		"Bool":           Bool(true),
+		"String":         String("hello"),
+		"Number":         Number(1.25),
+		"List":           NewList(String("a"), Number(2), NewList(Bool(false))),
+		"EmptyList":      NewList(),
+		"Object":         obj,
+		"Command":        NewUICommand().Name("appendchild").SourceID("b1"),
+		"MutationRecord": NewMutationRecord("ui", "text", String("hi")),
+		"Element":        ctor("b", storeid+"-b1"),
+	}
+}
+
+func TestMarshalValueRoundTrip(t *testing.T) {
+	for name, v := range builtinValues("codecstore") {
+		b, err := MarshalValue(v)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		r, err := UnmarshalValue(b)
+		if err != nil {
+			t.Fatalf("%s: %v in %s", name, err, b)
+		}
+		if !reflect.DeepEqual(r, v) {
+			t.Errorf("%s: got %#v, want %#v", name, r, v)
+		}
+
+		d, err := DecodeValue(v.RawValue())
+		if err != nil {
+			t.Fatalf("%s: DecodeValue: %v", name, err)
+		}
+		if !reflect.DeepEqual(d, v) {
+			t.Errorf("%s: DecodeValue: got %#v, want %#v", name, d, v)
+		}
+	}
+}
+
+func TestUnmarshalValueErrors(t *testing.T) {
+	tests := []struct {
+		payload string
+		err     error
+	}{
+		{`{"value":{"typ":"Bool","value":true}}`, ErrUnsupportedCodecVersion},
+		{`{"version":99,"value":{"typ":"Bool","value":true}}`, ErrUnsupportedCodecVersion},
+		{`{"version":1}`, ErrInvalidRawValue},
+		{`{"version":1,"value":{"typ":"Bool","value":1}}`, ErrInvalidRawValue},
+		{`{"version":1,"value":{"typ":"Int","value":"x"}}`, ErrInvalidRawValue},
+		{`{"version":1,"value":{"value":true}}`, ErrInvalidRawValue},
+		{`not json`, ErrInvalidRawValue},
+	}
+	for _, test := range tests {
+		_, err := UnmarshalValue([]byte(test.payload))
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: got %v, want %v", test.payload, err, test.err)
+		}
+	}
+	if _, err := MarshalValue(nil); !errors.Is(err, ErrInvalidRawValue) {
+		t.Errorf("MarshalValue(nil): got %v", err)
+	}
+}
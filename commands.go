@@ -44,7 +44,7 @@ func (c Command) Position(p int) Command {
 }
 
 func (c Command) Timestamp(t time.Time) Command {
-	Object(c).Set("timestamp", String(t.String()))
+	Object(c).Set("timestamp", Time(t))
 	return c
 }
 
@@ -100,6 +100,7 @@ func (e *Element) Mutate(command Command) *Element {
 }
 
 var DefaultCommandHandler = NewMutationHandler(func(evt MutationEvent) bool {
+	defer evt.Origin().enterMutationSource(SourceCommand)()
 	command, ok := evt.NewValue().(Command)
 	if !ok || (command.ValueType() != "Command") {
 		log.Print("Wrong format for command property value ")
@@ -256,4 +257,4 @@ var DefaultCommandHandler = NewMutationHandler(func(evt MutationEvent) bool {
 	default:
 		return true
 	}
-})
+}).Named("DefaultCommandHandler")
@@ -0,0 +1,155 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+import (
+	"log"
+)
+
+// Getter retrieves the value of a property on behalf of a computation.
+// Every property read through a Getter is recorded as a dependency of the
+// computed property.
+type Getter func(owner *Element, category string, propname string) (Value, bool)
+
+type dependency struct {
+	owner    *Element
+	category string
+	propname string
+}
+
+func (d dependency) key() string {
+	return d.owner.ID + "/" + d.category + "/" + d.propname
+}
+
+// computation holds the state of a computed property.
+type computation struct {
+	element  *Element
+	category string
+	propname string
+	fn       func(get Getter) Value
+
+	deps    map[string]*Subscription // indexed by dependency key
+	handler *MutationHandler
+	stale   bool
+	running bool
+}
+
+// Compute defines a property whose value is derived from other properties.
+// The dependencies of the computed property are the properties read via the
+// Getter passed to fn. They are watched automatically: when one of them
+// changes, the computed property is marked stale. It is recomputed right away
+// if the computed property is being watched, otherwise on its next retrieval
+// via Get.
+// Since the dependencies are recorded anew on each run, properties that are
+// no longer read stop being watched.
+// Calling Compute for a property that is already computed replaces the former
+// computation.
+func (e *Element) Compute(category string, propname string, fn func(get Getter) Value) *Element {
+	e.Uncompute(category, propname)
+	if e.computed == nil {
+		e.computed = make(map[string]*computation)
+	}
+	c := &computation{element: e, category: category, propname: propname, fn: fn, deps: make(map[string]*Subscription)}
+	c.handler = NewMutationHandler(func(evt MutationEvent) bool {
+		c.invalidate()
+		return false
+	})
+	e.computed[category+"/"+propname] = c
+	c.run()
+	return e
+}
+
+// Uncompute stops the computation of a property. The property keeps its last
+// computed value.
+func (e *Element) Uncompute(category string, propname string) *Element {
+	c, ok := e.computed[category+"/"+propname]
+	if !ok {
+		return e
+	}
+	for k, sub := range c.deps {
+		sub.Cancel()
+		delete(c.deps, k)
+	}
+	delete(e.computed, category+"/"+propname)
+	return e
+}
+
+// observed reports whether a MutationHandler has been registered for the
+// computed property.
+func (c *computation) observed() bool {
+	e := c.element
+	mhs, ok := e.PropMutationHandlers.list[e.ID+"/"+c.category+"/"+c.propname]
+	return ok && len(mhs.list) > 0
+}
+
+func (c *computation) invalidate() {
+	c.stale = true
+	if c.running || !c.observed() {
+		return
+	}
+	c.run()
+}
+
+// run evaluates the computation, updates the watched dependencies and stores
+// the result. The computation is evaluated again if a dependency changes while
+// it runs. If fn panics, the computed property remains stale.
+func (c *computation) run() {
+	if c.running {
+		return
+	}
+	v, ok := c.evaluate()
+	if !ok {
+		log.Printf("Element %s: %s/%s: dependencies kept changing while being computed", c.element.ID, c.category, c.propname)
+	}
+
+	defer c.element.enterMutationSource(SourceComputed)()
+	if err := c.element.Set(c.category, c.propname, v); err != nil {
+		log.Print(err)
+	}
+}
+
+// evaluate calls fn until no dependency changes during the call, up to
+// MaxPropagationDepth times, and watches the dependencies it reads. It reports
+// whether the returned value is up-to-date.
+func (c *computation) evaluate() (Value, bool) {
+	c.running = true
+	completed := false
+	defer func() {
+		c.running = false
+		if !completed {
+			c.stale = true // fn panicked
+		}
+	}()
+
+	var v Value
+	for i := 0; i < MaxPropagationDepth; i++ {
+		c.stale = false
+		deps := make(map[string]dependency)
+		get := func(owner *Element, category string, propname string) (Value, bool) {
+			if owner == c.element && category == c.category && propname == c.propname {
+				return owner.Properties.Get(category, propname) // a computation cannot depend on itself
+			}
+			d := dependency{owner, category, propname}
+			deps[d.key()] = d
+			return owner.Get(category, propname)
+		}
+		v = c.fn(get)
+
+		for k, sub := range c.deps {
+			if _, ok := deps[k]; !ok {
+				sub.Cancel()
+				delete(c.deps, k)
+			}
+		}
+		for k, d := range deps {
+			if sub, ok := c.deps[k]; !ok || !sub.Active() {
+				c.deps[k] = c.element.Watch(d.category, d.propname, d.owner, c.handler)
+			}
+		}
+		if !c.stale {
+			completed = true
+			return v, true
+		}
+	}
+	completed = true
+	return v, false
+}
@@ -0,0 +1,135 @@
+package ui
+
+import "testing"
+
+func TestComputeDependencies(t *testing.T) {
+	_, ctor := newTestStore("computestore")
+	a := ctor("a", "compute-a")
+	b := ctor("b", "compute-b")
+	a.SetData("useb", Bool(false))
+	a.SetData("x", Number(1))
+	b.SetData("y", Number(10))
+	runs := 0
+	a.Compute("data", "sum", func(get Getter) Value {
+		runs++
+		x, _ := get(a, "data", "x")
+		if u, _ := get(a, "data", "useb"); u == Bool(true) {
+			y, _ := get(b, "data", "y")
+			return x.(Number) + y.(Number)
+		}
+		return x
+	})
+	if v, _ := a.GetData("sum"); v != Number(1) || runs != 1 {
+		t.Fatalf("got %v after %d runs, want 1 after 1", v, runs)
+	}
+
+	// Unobserved computed properties are recomputed lazily.
+	a.SetData("x", Number(2))
+	a.SetData("x", Number(3))
+	if runs != 1 {
+		t.Errorf("recomputed %d times before being retrieved", runs-1)
+	}
+	if v, _ := a.GetData("sum"); v != Number(3) || runs != 2 {
+		t.Errorf("got %v after %d runs, want 3 after 2", v, runs)
+	}
+
+	// Observed computed properties are recomputed on change.
+	var got []Value
+	a.Watch("data", "sum", a, NewMutationHandler(func(evt MutationEvent) bool {
+		got = append(got, evt.NewValue())
+		return false
+	}))
+	a.SetData("useb", Bool(true))
+	b.SetData("y", Number(20))
+	if len(got) != 2 || got[0] != Number(13) || got[1] != Number(23) {
+		t.Errorf("got %v, want [13 23]", got)
+	}
+
+	// Dependencies which are no longer read stop being watched.
+	a.SetData("useb", Bool(false))
+	n, r := len(got), runs
+	b.SetData("y", Number(30))
+	if len(got) != n || runs != r {
+		t.Error("recomputed after a former dependency changed")
+	}
+
+	a.Uncompute("data", "sum")
+	a.SetData("x", Number(100))
+	if v, _ := a.GetData("sum"); v != Number(3) {
+		t.Errorf("got %v, want the last computed value", v)
+	}
+}
+
+func TestComputeDispose(t *testing.T) {
+	s, ctor := newTestStore("computedisposestore")
+	a := ctor("a", "computedispose-a")
+	b := ctor("b", "computedispose-b")
+	runs := 0
+	a.Compute("data", "double", func(get Getter) Value {
+		runs++
+		y, _ := get(b, "data", "y")
+		if y == nil {
+			return Number(0)
+		}
+		return y.(Number) * 2
+	})
+	a.Watch("data", "double", a, NewMutationHandler(func(MutationEvent) bool { return false }))
+
+	s.RemoveByID(a.ID)
+	b.SetData("y", Number(2))
+	if runs != 1 {
+		t.Errorf("recomputed %d times after disposal", runs-1)
+	}
+	if mhs, ok := b.PropMutationHandlers.list[b.ID+"/data/y"]; ok && len(mhs.list) > 0 {
+		t.Errorf("%d handlers still registered on the dependency", len(mhs.list))
+	}
+}
+
+func TestComputePanic(t *testing.T) {
+	_, ctor := newTestStore("computepanicstore")
+	a := ctor("a", "computepanic-a")
+	a.SetData("x", Number(1))
+	a.Compute("data", "inverse", func(get Getter) Value {
+		x, _ := get(a, "data", "x")
+		if x == Number(0) {
+			panic("division by zero")
+		}
+		return 1 / x.(Number)
+	})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic not propagated")
+			}
+		}()
+		a.SetData("x", Number(0))
+		a.GetData("inverse")
+	}()
+
+	a.SetData("x", Number(4))
+	if v, _ := a.GetData("inverse"); v != Number(0.25) {
+		t.Errorf("got %v, want 0.25: computed property no longer updated after a panic", v)
+	}
+}
+
+func TestComputeInvalidatedWhileRunning(t *testing.T) {
+	_, ctor := newTestStore("computerunstore")
+	a := ctor("a", "computerun-a")
+	a.SetData("x", Number(1))
+	runs := 0
+	a.Compute("data", "copy", func(get Getter) Value {
+		runs++
+		x, _ := get(a, "data", "x")
+		if runs == 2 {
+			a.SetData("x", Number(5)) // a dependency changes during the run
+		}
+		return x
+	})
+	a.Watch("data", "copy", a, NewMutationHandler(func(MutationEvent) bool { return false }))
+
+	a.SetData("x", Number(2))
+	if v, _ := a.Properties.Get("data", "copy"); v != Number(5) {
+		t.Errorf("got %v, want 5: change during the computation lost", v)
+	}
+}
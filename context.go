@@ -0,0 +1,106 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+// Context
+//
+// An Element may provide values to its subtree under a key, e.g. the current
+// user or locale. The values are stored in its "context" property category.
+// A descendant consumes the value provided by its nearest provider for a key.
+// The consumed value is mirrored in its "consumed" property category so that
+// it can be watched: the mirror is updated whenever the provider changes the
+// value, or the consumer is attached under a different provider.
+
+// Provide makes a value available under the given key to the descendants of
+// the Element. Descendants which consumed the key from a farther provider are
+// switched to this one.
+func (e *Element) Provide(key string, value Value) *Element {
+	_, provided := e.Properties.Get("context", key)
+	e.Set("context", key, value)
+	if !provided {
+		resolveSubtreeContext(e, key)
+	}
+	return e
+}
+
+// Consume returns the value provided under the given key by the nearest
+// ancestor and registers the Element as a consumer of the key. From then on,
+// the ("consumed", key) property of the Element holds the value in effect, and
+// is deleted when no ancestor provides it.
+func (e *Element) Consume(key string) (Value, bool) {
+	if e.contexts == nil {
+		e.contexts = make(map[string]*Subscription)
+	}
+	if _, ok := e.contexts[key]; !ok {
+		e.contexts[key] = nil
+		e.resolveContext(key)
+	}
+	return e.Get("consumed", key)
+}
+
+// contextProvider returns the nearest ancestor providing a value for the key.
+func (e *Element) contextProvider(key string) *Element {
+	if e.path == nil {
+		return nil
+	}
+	for k := len(e.path.List) - 1; k >= 0; k-- {
+		ancestor := e.path.List[k]
+		if _, ok := ancestor.Properties.Get("context", key); ok {
+			return ancestor
+		}
+	}
+	return nil
+}
+
+// resolveContext looks up the provider of a consumed key, watches it and
+// updates the consumed value.
+func (e *Element) resolveContext(key string) {
+	provider := e.contextProvider(key)
+	sub := e.contexts[key]
+	if !sub.Active() || sub.Target() != provider {
+		sub.Cancel()
+		sub = nil
+		if provider != nil {
+			sub = e.Watch("context", key, provider, NewMutationHandler(func(evt MutationEvent) bool {
+				e.resolveContext(key)
+				return false
+			}))
+		}
+		e.contexts[key] = sub
+	}
+
+	if provider == nil {
+		if _, ok := e.Properties.Get("consumed", key); ok {
+			e.Delete("consumed", key)
+		}
+		return
+	}
+	v, _ := provider.Properties.Get("context", key)
+	e.Set("consumed", key, v)
+}
+
+// resolveContexts updates every consumed value of an Element which has just
+// been attached or detached.
+func (e *Element) resolveContexts() {
+	for key := range e.contexts {
+		e.resolveContext(key)
+	}
+}
+
+// resolveSubtreeContext updates the consumers of a key within the subtree
+// rooted at e.
+func resolveSubtreeContext(e *Element, key string) {
+	for _, child := range e.Children.List {
+		if _, ok := child.contexts[key]; ok {
+			child.resolveContext(key)
+		}
+		resolveSubtreeContext(child, key)
+	}
+	for _, view := range e.InactiveViews {
+		for _, child := range view.Elements().List {
+			if _, ok := child.contexts[key]; ok {
+				child.resolveContext(key)
+			}
+			resolveSubtreeContext(child, key)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package ui
+
+import "testing"
+
+// contextTree returns an app root with the root > outer > inner > leaf chain
+// of Elements.
+func contextTree(storeid string) (root, outer, inner, leaf *Element, thing func(name string, id string, optionNames ...string) *Element) {
+	s, thing := newTestStore(storeid)
+	root = s.NewAppRoot(storeid + "-root")
+	outer = thing("outer", storeid+"-outer")
+	inner = thing("inner", storeid+"-inner")
+	leaf = thing("leaf", storeid+"-leaf")
+	root.AppendChild(outer)
+	outer.AppendChild(inner)
+	inner.AppendChild(leaf)
+	return root, outer, inner, leaf, thing
+}
+
+// consumed returns the value consumed by e under the key, or nil.
+func consumed(e *Element, key string) Value {
+	v, _ := e.Get("consumed", key)
+	return v
+}
+
+func TestContextNearestProvider(t *testing.T) {
+	root, outer, inner, leaf, _ := contextTree("contextnearest")
+	root.Provide("locale", String("en"))
+	inner.Provide("locale", String("fr"))
+	if v, ok := leaf.Consume("locale"); !ok || !Equal(v, String("fr")) {
+		t.Errorf("got %v, want the value of the nearest provider", v)
+	}
+
+	// a provider added between the consumer and its provider takes over
+	outer.Provide("theme", String("dark"))
+	if v, _ := leaf.Consume("theme"); !Equal(v, String("dark")) {
+		t.Fatalf("got %v, want dark", v)
+	}
+	inner.Provide("theme", String("light"))
+	if v := consumed(leaf, "theme"); !Equal(v, String("light")) {
+		t.Errorf("got %v, want the value of the new nearest provider", v)
+	}
+}
+
+func TestContextProviderChange(t *testing.T) {
+	root, _, inner, leaf, _ := contextTree("contextchange")
+	root.Provide("locale", String("en"))
+	inner.Provide("locale", String("fr"))
+	leaf.Consume("locale")
+	var got []Value
+	leaf.Watch("consumed", "locale", leaf, NewMutationHandler(func(evt MutationEvent) bool {
+		got = append(got, evt.NewValue())
+		return false
+	}))
+
+	inner.Provide("locale", String("de"))
+	if len(got) != 1 || !Equal(got[0], String("de")) {
+		t.Errorf("got %v, want [de]", got)
+	}
+	root.Provide("locale", String("es"))
+	if len(got) != 1 {
+		t.Errorf("notified of a change of a shadowed provider: %v", got)
+	}
+}
+
+func TestContextConsumerMoved(t *testing.T) {
+	root, outer, _, leaf, thing := contextTree("contextmoved")
+	outer.Provide("locale", String("fr"))
+	leaf.Consume("locale")
+
+	other := thing("other", "contextmoved-other")
+	other.Provide("locale", String("de"))
+	root.AppendChild(other)
+	other.AppendChild(leaf)
+	if v := consumed(leaf, "locale"); !Equal(v, String("de")) {
+		t.Errorf("got %v, want the value of the new provider", v)
+	}
+
+	outer.Provide("locale", String("es"))
+	if v := consumed(leaf, "locale"); !Equal(v, String("de")) {
+		t.Errorf("notified by the former provider: got %v", v)
+	}
+	other.Provide("locale", String("it"))
+	if v := consumed(leaf, "locale"); !Equal(v, String("it")) {
+		t.Errorf("got %v, want it", v)
+	}
+
+	other.removeChild(leaf)
+	if v, ok := leaf.Get("consumed", "locale"); ok {
+		t.Errorf("got %v consumed after detachment, want none", v)
+	}
+}
+
+func TestContextProviderDeletion(t *testing.T) {
+	root, _, inner, leaf, _ := contextTree("contextdeletion")
+	root.Provide("locale", String("en"))
+	inner.Provide("locale", String("fr"))
+	leaf.Consume("locale")
+
+	inner.Delete("context", "locale")
+	if v := consumed(leaf, "locale"); !Equal(v, String("en")) {
+		t.Errorf("got %v, want the value of the outer provider", v)
+	}
+	root.Provide("locale", String("es"))
+	if v := consumed(leaf, "locale"); !Equal(v, String("es")) {
+		t.Errorf("got %v, want es", v)
+	}
+
+	root.Delete("context", "locale")
+	if v, ok := leaf.Get("consumed", "locale"); ok {
+		t.Errorf("got %v consumed without provider, want none", v)
+	}
+}
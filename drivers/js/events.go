@@ -32,40 +32,9 @@ var NativeEventBridge = func(NativeEventName string, target *ui.Element) {
 		evt := args[0]
 		evt.Call("stopPropagation")
 
-		// Time to create the corresponding GoEvent
-		typ := evt.Get("type").String()
-		bubbles := evt.Get("bubbles").Bool()
-		cancancel := evt.Get("cancelable").Bool()
-		var target *ui.Element
-		targetid := evt.Get("target").Get("id")
-		value := evt.Get("target").Get("value").String()
-		if targetid.Truthy() {
-			target = Elements.GetByID(targetid.String())
-		} else {
-			// this might be a stretch... but we assume that the only element without
-			// a native side ID is the window in javascript.
-			target = GetWindow().Element()
-		}
-
-		var nativeEvent interface{}
-		nativeEvent = evt
-		if cancancel {
-			nativeEvent = cancelable{evt}
-		}
-		if typ == "popstate" || typ == "load" {
-			//value = js.Global().Get("document").Get("URL").String()
-			value = js.Global().Get("location").Get("pathname").String()
-			/*u,err:= url.ParseRequestURI(value)
-			if err!= nil{
-				value = ""
-			} else{
-				value = u.Path
-			}*/
-
-		}
-		goevt := ui.NewEvent(typ, bubbles, cancancel, target, nativeEvent, value)
-
-		target.DispatchEvent(goevt, nil)
+		// The event is dispatched on the UI thread. If UI work is in progress,
+		// it is queued and dispatched once that work has completed.
+		Elements.Do(func() { dispatchNativeEvent(evt) })
 		return nil
 	})
 
@@ -90,3 +59,40 @@ var NativeEventBridge = func(NativeEventName string, target *ui.Element) {
 	}
 
 }
+
+// dispatchNativeEvent dispatches the GoEvent corresponding to a native event.
+func dispatchNativeEvent(evt js.Value) {
+	typ := evt.Get("type").String()
+	bubbles := evt.Get("bubbles").Bool()
+	cancancel := evt.Get("cancelable").Bool()
+	var target *ui.Element
+	targetid := evt.Get("target").Get("id")
+	value := evt.Get("target").Get("value").String()
+	if targetid.Truthy() {
+		target = Elements.GetByID(targetid.String())
+	} else {
+		// this might be a stretch... but we assume that the only element without
+		// a native side ID is the window in javascript.
+		target = GetWindow().Element()
+	}
+
+	var nativeEvent interface{}
+	nativeEvent = evt
+	if cancancel {
+		nativeEvent = cancelable{evt}
+	}
+	if typ == "popstate" || typ == "load" {
+		//value = js.Global().Get("document").Get("URL").String()
+		value = js.Global().Get("location").Get("pathname").String()
+		/*u,err:= url.ParseRequestURI(value)
+		if err!= nil{
+			value = ""
+		} else{
+			value = u.Path
+		}*/
+
+	}
+	goevt := ui.NewEvent(typ, bubbles, cancancel, target, nativeEvent, value)
+
+	target.DispatchEvent(goevt, nil)
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"math"
 	"strconv"
 	"strings"
 	"syscall/js"
@@ -25,6 +26,22 @@ var (
 
 var NewID = ui.NewIDgenerator(56813256545869)
 
+// AnimationFrameTick can be used as the Tick of a ui.Scheduler so that queued
+// MutationEvents are dispatched before the next repaint:
+//
+//	Elements.SetScheduler(ui.NewScheduler(AnimationFrameTick))
+//
+// The flush is submitted to the UI thread via Elements.Do.
+func AnimationFrameTick(flush func()) {
+	var cb js.Func
+	cb = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		cb.Release()
+		Elements.Do(flush)
+		return nil
+	})
+	js.Global().Call("requestAnimationFrame", cb)
+}
+
 // mutationCaptureMode describes how a Go App may capture textarea value changes
 // that happen in native javascript. For instance, when a blur event is dispatched
 // or when any mutation is observed via the MutationObserver API.
@@ -151,29 +168,28 @@ func storer(s string) func(element *ui.Element, category string, propname string
 				store.Set(element.ID+"/"+category, v)
 			}
 		}
-		item := value.RawValue()
-		v := stringify(item)
-		store.Set(element.ID+"/"+category+"/"+propname, js.ValueOf(v))
+		v, err := ui.MarshalValue(value)
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		store.Set(element.ID+"/"+category+"/"+propname, js.ValueOf(string(v)))
 		return
 	}
 }
 
-/*func stringify(v interface{}) js.Value {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Print(v)
-			log.Print(r)
-		}
-	}()
-	res := js.ValueOf(v)
-	return res
-}*/
-func stringify(v interface{}) string {
-	res, err := json.Marshal(v)
-	if err != nil {
-		panic(err)
+// unmarshalStoredValue decodes a persisted property value. Values persisted
+// before the introduction of the versioned codec are raw Objects without
+// envelope: they are decoded as such.
+func unmarshalStoredValue(b []byte) (ui.Value, error) {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return nil, err
+	}
+	if _, ok := payload["version"]; !ok {
+		return ui.DecodeValue(ui.Object(payload))
 	}
-	return string(res)
+	return ui.UnmarshalValue(b)
 }
 
 var sessionstorefn = storer("sessionStorage")
@@ -181,6 +197,7 @@ var localstoragefn = storer("localStorage")
 
 func loader(s string) func(e *ui.Element) error {
 	return func(e *ui.Element) error {
+		defer e.ElementStore.EnterMutationSource(ui.SourceLoad)()
 		store := jsStore{js.Global().Get(s)}
 		id := e.ID
 
@@ -217,22 +234,21 @@ func loader(s string) func(e *ui.Element) error {
 				propname := proptypename[1]
 				jsonvalue, ok := store.Get(e.ID + "/" + category + "/" + propname)
 				if ok {
-					var rawvaluemapstring string
-					err = json.Unmarshal([]byte(jsonvalue.String()), &rawvaluemapstring)
+					var rawvaluestring string
+					err = json.Unmarshal([]byte(jsonvalue.String()), &rawvaluestring)
 					if err != nil {
 						return err
 					}
-					rawvalue := ui.NewObject()
-					err = json.Unmarshal([]byte(rawvaluemapstring), &rawvalue)
+					value, err := unmarshalStoredValue([]byte(rawvaluestring))
 					if err != nil {
 						return err
 					}
 					if !(category == "ui" && propname == "mutationrecords") {
-						ui.LoadProperty(e, category, propname, proptype, rawvalue.Value())
-						//log.Print("LOADED PROPMAP: ", e.Properties, category, propname, rawvalue.Value()) // DEBUG
+						ui.LoadProperty(e, category, propname, proptype, value)
+						//log.Print("LOADED PROPMAP: ", e.Properties, category, propname, value) // DEBUG
 					} else {
-						ui.LoadProperty(e, category, propname, proptype, rawvalue.Value())
-						rawmutationrecords := rawvalue.Value()
+						ui.LoadProperty(e, category, propname, proptype, value)
+						rawmutationrecords := value
 						// log.Print("mutationrecords storage...", rawvalue.ValueType(), rawmutationrecords) // DEBUG
 						if rawmutationrecords.ValueType() != "List" {
 							return errors.New("mutationrecords are not of type List")
@@ -274,8 +290,9 @@ func loader(s string) func(e *ui.Element) error {
 							if !ok {
 								return errors.New("value should implement ui.Value")
 							}
+							old, existed := e.Properties.Get(string(category), string(propname))
 							e.Properties.Set(string(category), string(propname), tval)
-							evt := e.NewMutationEvent(string(category), string(propname), tval)
+							evt := e.NewMutationEvent(string(category), string(propname), tval).WithOldValue(old, existed)
 							e.PropMutationHandlers.DispatchEvent(evt)
 						}
 					}
@@ -409,7 +426,7 @@ func (n NativeElement) InsertChild(child *ui.Element, index int) {
 		return
 	}
 	r := childlist.Call("item", index)
-	n.JSValue().Call("insertBefore", v, r)
+	n.JSValue().Call("insertBefore", v.JSValue(), r)
 }
 
 func (n NativeElement) ReplaceChild(old *ui.Element, new *ui.Element) {
@@ -739,6 +756,16 @@ func (t TextArea) SetRows(i int) TextArea {
 	return t
 }
 
+// textAreaSchema describes the properties of textarea Elements.
+var textAreaSchema = ui.NewSchema(
+	ui.NewPropertySchema("data", "text", "String"),
+	ui.NewPropertySchema("data", "rows", "Number").Between(0, math.MaxInt32),
+	ui.NewPropertySchema("data", "cols", "Number").Between(0, math.MaxInt32),
+	ui.NewPropertySchema("ui", "text", "String"),
+	ui.NewPropertySchema("ui", "rows", "Number").Between(0, math.MaxInt32),
+	ui.NewPropertySchema("ui", "cols", "Number").Between(0, math.MaxInt32),
+)
+
 // NewTextArea is a constructor for a textarea html element.
 func NewTextArea(name string, id string, rows int, cols int, options ...string) TextArea {
 	t := Elements.NewConstructor("textarea", func(ename string, eid string) *ui.Element {
@@ -772,7 +799,7 @@ func NewTextArea(name string, id string, rows int, cols int, options ...string)
 
 		e.Watch("ui", "cols", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
 			if n, ok := evt.NewValue().(ui.Number); ok {
-				SetAttribute(e, "rows", strconv.Itoa(int(n)))
+				SetAttribute(e, "cols", strconv.Itoa(int(n)))
 				return false
 			}
 			return true
@@ -782,10 +809,10 @@ func NewTextArea(name string, id string, rows int, cols int, options ...string)
 		e.Native = n
 		SetAttribute(e, "name", ename)
 		SetAttribute(e, "id", eid)
-		e.SetDataSyncUI("rows", ui.String(strconv.Itoa(rows)))
-		e.SetDataSyncUI("cols", ui.String(strconv.Itoa(cols)))
+		e.SetDataSyncUI("rows", ui.Number(rows))
+		e.SetDataSyncUI("cols", ui.Number(cols))
 		return e
-	}, allowTextAreaDataBindingOnBlur, allowTextAreaDataBindingOnInput, AllowTooltip, AllowSessionStoragePersistence, AllowAppLocalStoragePersistence)
+	}, allowTextAreaDataBindingOnBlur, allowTextAreaDataBindingOnInput, AllowTooltip, AllowSessionStoragePersistence, AllowAppLocalStoragePersistence, ui.WithSchema(textAreaSchema))
 	return TextArea{tryLoad(t(name, id, options...))}
 }
 
@@ -1841,22 +1868,28 @@ func ListValueInfo(v ui.Value) (index int, newvalue ui.Value, ok bool) {
 	return int(idx), tval, true
 }
 
-func listAppend(list *ui.Element, values ...ui.Value) *ui.Element {
-	var backinglist ui.List
-
+// backingList returns the list of values held by a list Element.
+// It is immutable so that modifications have to go through Element.Set.
+func backingList(list *ui.Element) ui.ImmutableList {
 	bkglist, ok := list.Get("internals", list.Name)
 	if !ok {
-		backinglist = ui.NewList()
+		return ui.NewImmutableList()
 	}
-	backinglist, ok = bkglist.(ui.List)
-	if !ok {
-		backinglist = ui.NewList()
+	switch t := bkglist.(type) {
+	case ui.ImmutableList:
+		return t
+	case ui.List: // lists persisted before backing lists were made immutable
+		return ui.NewImmutableList(t...)
+	default:
+		return ui.NewImmutableList()
 	}
+}
 
-	length := len(backinglist)
+func listAppend(list *ui.Element, values ...ui.Value) *ui.Element {
+	backinglist := backingList(list)
+	length := backinglist.Len()
 
-	backinglist = append(backinglist, values...)
-	list.Set("internals", list.Name, backinglist, false)
+	list.Set("internals", list.Name, backinglist.Append(values...), false)
 	for i, value := range values {
 		list.Set(list.Name, "append", newListValue(i+length, value), false)
 	}
@@ -1864,19 +1897,9 @@ func listAppend(list *ui.Element, values ...ui.Value) *ui.Element {
 }
 
 func listPrepend(list *ui.Element, values ...ui.Value) *ui.Element {
-	var backinglist ui.List
-
-	bkglist, ok := list.Get("internals", list.Name)
-	if !ok {
-		backinglist = ui.NewList()
-	}
-	backinglist, ok = bkglist.(ui.List)
-	if !ok {
-		backinglist = ui.NewList()
-	}
+	backinglist := backingList(list)
 
-	backinglist = append(values, backinglist...)
-	list.Set("internals", list.Name, backinglist, false)
+	list.Set("internals", list.Name, backinglist.InsertAt(0, values...), false)
 	for i := len(values) - 1; i >= 0; i-- {
 		list.Set(list.Name, "prepend", newListValue(i, values[i]), false)
 	}
@@ -1884,28 +1907,15 @@ func listPrepend(list *ui.Element, values ...ui.Value) *ui.Element {
 }
 
 func listInsertAt(list *ui.Element, offset int, values ...ui.Value) *ui.Element {
-	var backinglist ui.List
+	backinglist := backingList(list)
 
-	bkglist, ok := list.Get("internals", list.Name)
-	if !ok {
-		backinglist = ui.NewList()
-	}
-	backinglist, ok = bkglist.(ui.List)
-	if !ok {
-		backinglist = ui.NewList()
-	}
-
-	length := len(backinglist)
+	length := backinglist.Len()
 	if offset >= length || offset <= 0 {
 		log.Print("Cannot insert element in list at that position.")
 		return list
 	}
 
-	nel := ui.NewList(backinglist[:offset]...)
-	nel = append(nel, values...)
-	nel = append(nel, backinglist[offset:]...)
-	backinglist = nel
-	list.Set("internals", list.Name, backinglist, false)
+	list.Set("internals", list.Name, backinglist.InsertAt(offset, values...), false)
 	for i, value := range values {
 		list.Set(list.Name, "insert", newListValue(offset+i, value), false)
 	}
@@ -1913,25 +1923,15 @@ func listInsertAt(list *ui.Element, offset int, values ...ui.Value) *ui.Element
 }
 
 func listDelete(list *ui.Element, offset int) *ui.Element {
-	var backinglist ui.List
-
-	bkglist, ok := list.Get("internals", list.Name)
-	if !ok {
-		backinglist = ui.NewList()
-	}
-	backinglist, ok = bkglist.(ui.List)
-	if !ok {
-		backinglist = ui.NewList()
-	}
+	backinglist := backingList(list)
 
-	length := len(backinglist)
+	length := backinglist.Len()
 	if offset >= length || offset <= 0 {
 		log.Print("Cannot insert element in list at that position.")
 		return list
 	}
-	backinglist = append(backinglist[:offset], backinglist[offset+1:])
-	list.Set("internals", list.Name, backinglist, false)
-	list.Set(list.Name, "delete", newListValue(offset, nil), false)
+	list.Set("internals", list.Name, backinglist.RemoveAt(offset), false)
+	list.Set(list.Name, "delete", newListValue(offset, ui.Null), false)
 	return list
 }
 
@@ -0,0 +1,219 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Equal reports whether two Values are structurally equal.
+// Objects and Lists are compared recursively. Elements are equal only if they
+// are the same *Element. Application-defined Values are compared via their raw
+// representation.
+func Equal(a, b Value) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	switch t := a.(type) {
+	case Bool, String, Number, Int, NullValue:
+		return a == b
+	case Bytes:
+		return bytes.Equal(t, b.(Bytes))
+	case Time:
+		return time.Time(t).Equal(time.Time(b.(Time)))
+	case *Element:
+		return t == b.(*Element)
+	case Object:
+		return equalObjects(t, b.(Object))
+	case Command:
+		return equalObjects(Object(t), Object(b.(Command)))
+	case MutationRecord:
+		return equalObjects(Object(t), Object(b.(MutationRecord)))
+	case List:
+		l := b.(List)
+		if len(t) != len(l) {
+			return false
+		}
+		for i := range t {
+			if !Equal(t[i], l[i]) {
+				return false
+			}
+		}
+		return true
+	case ImmutableObject:
+		o := b.(ImmutableObject)
+		if len(t.fields) != len(o.fields) {
+			return false
+		}
+		for k, v := range t.fields {
+			w, ok := o.fields[k]
+			if !ok || !Equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case ImmutableList:
+		return Equal(List(t.items), List(b.(ImmutableList).items))
+	default:
+		if a.ValueType() != b.ValueType() {
+			return false
+		}
+		return reflect.DeepEqual(a.RawValue(), b.RawValue())
+	}
+}
+
+func equalObjects(a, b Object) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, va := range a {
+		vb, ok := b[k]
+		if !ok {
+			return false
+		}
+		if !equalEntries(va, vb) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalEntries(a, b interface{}) bool {
+	va, oka := a.(Value)
+	vb, okb := b.(Value)
+	if oka && okb {
+		return Equal(va, vb)
+	}
+	if oka != okb {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// ValuePatch describes an elementary change between two Values, as returned by
+// Diff.
+// Path is the dot-separated list of Object keys and List indices leading to the
+// changed Value. The empty path denotes the Value itself.
+// Op is one of "add", "remove" or "replace".
+type ValuePatch struct {
+	Op   string
+	Path string
+	Old  Value
+	New  Value
+}
+
+// Diff returns the list of patches that turn Value a into Value b.
+// It returns nil if both Values are equal.
+// Objects (including Commands and MutationRecords) and Lists are compared
+// recursively. Any other change is reported as a replacement.
+func Diff(a, b Value) []ValuePatch {
+	return diff(a, b, "", nil)
+}
+
+func diff(a, b Value, path string, patches []ValuePatch) []ValuePatch {
+	if Equal(a, b) {
+		return patches
+	}
+	if a == nil || b == nil || reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return append(patches, ValuePatch{"replace", path, a, b})
+	}
+	switch t := a.(type) {
+	case Object:
+		return diffObjects(t, b.(Object), path, patches)
+	case Command:
+		return diffObjects(Object(t), Object(b.(Command)), path, patches)
+	case MutationRecord:
+		return diffObjects(Object(t), Object(b.(MutationRecord)), path, patches)
+	case List:
+		l := b.(List)
+		for i := range t {
+			p := joinPath(path, strconv.Itoa(i))
+			if i >= len(l) {
+				patches = append(patches, ValuePatch{"remove", p, t[i], nil})
+				continue
+			}
+			patches = diff(t[i], l[i], p, patches)
+		}
+		for i := len(t); i < len(l); i++ {
+			patches = append(patches, ValuePatch{"add", joinPath(path, strconv.Itoa(i)), nil, l[i]})
+		}
+		return patches
+	case ImmutableObject:
+		return diffObjects(t.Object(), b.(ImmutableObject).Object(), path, patches)
+	case ImmutableList:
+		return diff(List(t.items), List(b.(ImmutableList).items), path, patches)
+	default:
+		return append(patches, ValuePatch{"replace", path, a, b})
+	}
+}
+
+func diffObjects(a, b Object, path string, patches []ValuePatch) []ValuePatch {
+	if a.ValueType() != b.ValueType() {
+		return append(patches, ValuePatch{"replace", path, a, b})
+	}
+	// Entries that are not Values cannot be described by a patch: the whole
+	// Object is replaced instead.
+	for k, va := range a {
+		vb, ok := b[k]
+		if k == "typ" || (ok && equalEntries(va, vb)) {
+			continue
+		}
+		_, oka := va.(Value)
+		_, okb := vb.(Value)
+		if (va != nil && !oka) || (ok && vb != nil && !okb) {
+			return append(patches, ValuePatch{"replace", path, a, b})
+		}
+	}
+	for k, vb := range b {
+		if _, ok := a[k]; ok {
+			continue
+		}
+		if _, okb := vb.(Value); vb != nil && !okb {
+			return append(patches, ValuePatch{"replace", path, a, b})
+		}
+	}
+
+	for _, k := range sortedKeys(a) {
+		if k == "typ" {
+			continue
+		}
+		oldv, _ := a[k].(Value)
+		vb, ok := b[k]
+		if !ok {
+			patches = append(patches, ValuePatch{"remove", joinPath(path, k), oldv, nil})
+			continue
+		}
+		newv, _ := vb.(Value)
+		patches = diff(oldv, newv, joinPath(path, k), patches)
+	}
+	for _, k := range sortedKeys(b) {
+		if _, ok := a[k]; ok {
+			continue
+		}
+		newv, _ := b[k].(Value)
+		patches = append(patches, ValuePatch{"add", joinPath(path, k), nil, newv})
+	}
+	return patches
+}
+
+func sortedKeys(o Object) []string {
+	keys := make([]string, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	obj := func(l List) Object {
+		o := NewObject()
+		o.Set("l", l)
+		o.Set("b", Bool(true))
+		return o
+	}
+	tests := []struct {
+		name  string
+		a, b  Value
+		equal bool
+	}{
+		{"nested Objects", obj(NewList(Number(1), NewList(String("a")))), obj(NewList(Number(1), NewList(String("a")))), true},
+		{"nested List items differ", obj(NewList(Number(1), NewList(String("a")))), obj(NewList(Number(1), NewList(String("b")))), false},
+		{"List lengths differ", NewList(Number(1)), NewList(Number(1), Number(1)), false},
+		{"missing Object entry", obj(NewList()), NewObject(), false},
+		{"Object and Command", obj(NewList()), Command(obj(NewList())), false},
+		{"Number and String", Number(1), String("1"), false},
+		{"nil", nil, nil, true},
+		{"nil and Value", nil, Bool(false), false},
+	}
+	for _, test := range tests {
+		if got := Equal(test.a, test.b); got != test.equal {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.equal)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := NewObject()
+	a.Set("x", NewList(Number(1), String("a")))
+	a.Set("y", Bool(true))
+	b := NewObject()
+	b.Set("x", NewList(Number(1), String("b"), Number(3)))
+	b.Set("z", Bool(true))
+
+	want := []ValuePatch{
+		{"replace", "x.1", String("a"), String("b")},
+		{"add", "x.2", nil, Number(3)},
+		{"remove", "y", Bool(true), nil},
+		{"add", "z", nil, Bool(true)},
+	}
+	if got := Diff(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := Diff(a, a); got != nil {
+		t.Errorf("equal Values: got %+v, want nil", got)
+	}
+	if got := Diff(Number(1), String("1")); !reflect.DeepEqual(got, []ValuePatch{{"replace", "", Number(1), String("1")}}) {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestMutationDeduplication(t *testing.T) {
+	_, ctor := newTestStore("dedupstore", AllowMutationDeduplication)
+	tests := []struct {
+		name    string
+		options []string
+		want    int
+	}{
+		{"without deduplication", nil, 3},
+		{"with deduplication", []string{EnableMutationDeduplication()}, 2},
+	}
+	for _, test := range tests {
+		e := ctor("e", "dedup-"+test.name, test.options...)
+		var got []string
+		e.Watch("data", "p", e, recorder(&got, "p"))
+		e.SetData("p", NewList(String("a")))
+		e.SetData("p", NewList(String("a")))
+		e.SetData("p", NewList(String("b")))
+		if len(got) != test.want {
+			t.Errorf("%s: got %d dispatches, want %d", test.name, len(got), test.want)
+		}
+	}
+}
@@ -97,6 +97,11 @@ func (e EventListeners) RemoveEventHandler(event string, handler *EventHandler)
 	eh.Remove(handler)
 }
 
+func (e EventListeners) hasHandlers(event string) bool {
+	eh, ok := e.list[event]
+	return ok && len(eh.List) > 0
+}
+
 func (e EventListeners) Handle(evt Event) bool {
 	evh, ok := e.list[evt.Type()]
 	if !ok {
@@ -170,7 +175,7 @@ func (e *eventHandlers) Add(h *EventHandler) *eventHandlers {
 }
 
 func (e *eventHandlers) Remove(h *EventHandler) *eventHandlers {
-	var index int
+	index := -1
 	for k, v := range e.List {
 		if v != h {
 			continue
@@ -179,7 +184,11 @@ func (e *eventHandlers) Remove(h *EventHandler) *eventHandlers {
 		break
 	}
 	if index >= 0 {
-		e.List = append(e.List[:index], e.List[index+1:]...)
+		// a new slice is allocated so that an ongoing iteration over the
+		// handlers is not disrupted
+		l := make([]*EventHandler, 0, len(e.List)-1)
+		l = append(l, e.List[:index]...)
+		e.List = append(l, e.List[index+1:]...)
 	}
 	return e
 }
@@ -0,0 +1,18 @@
+package ui
+
+// newTestStore returns a new ElementStore along with the constructor of a plain
+// Element, registered in it under the "thing" name with the given options.
+func newTestStore(storeid string, options ...ConstructorOption) (*ElementStore, func(name string, id string, optionNames ...string) *Element) {
+	s := NewElementStore(storeid, "test")
+	ctor := s.NewConstructor("thing", func(name, id string) *Element { return NewElement(name, id, "test") }, options...)
+	return s, ctor
+}
+
+// recorder returns a MutationHandler which appends the given label to *got each
+// time it is called.
+func recorder(got *[]string, label string) *MutationHandler {
+	return NewMutationHandler(func(evt MutationEvent) bool {
+		*got = append(*got, label)
+		return false
+	})
+}
@@ -0,0 +1,207 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+import (
+	"sort"
+)
+
+// ImmutableObject is a read-only counterpart of Object.
+// Its update methods return a modified copy and leave the receiver untouched
+// so that a value stored in a PropertyStore can be shared safely: any change
+// has to be stored back with Element.Set in order to be observable.
+type ImmutableObject struct {
+	fields map[string]Value
+}
+
+// NewImmutableObject returns an ImmutableObject holding a copy of the Value
+// entries of an Object. Nested Values are frozen as well, see Freeze.
+// Entries which are not Values, such as the raw fields of an Object obtained
+// via RawValue, are not retained.
+func NewImmutableObject(o Object) ImmutableObject {
+	fields := make(map[string]Value, len(o))
+	for k, val := range o {
+		if k == "typ" {
+			continue
+		}
+		v, ok := val.(Value)
+		if !ok && val != nil {
+			continue
+		}
+		fields[k] = Freeze(v)
+	}
+	return ImmutableObject{fields}
+}
+
+func (o ImmutableObject) discriminant() discriminant { return "particleui" }
+func (o ImmutableObject) ValueType() string          { return "ImmutableObject" }
+func (o ImmutableObject) RawValue() Object {
+	return Object(o.Object()).SetType("ImmutableObject").RawValue()
+}
+
+// Get returns the Value stored under the given key.
+func (o ImmutableObject) Get(key string) (Value, bool) {
+	v, ok := o.fields[key]
+	if !ok {
+		return nil, false
+	}
+	return copyValue(v), true
+}
+
+// Len returns the number of entries of the ImmutableObject.
+func (o ImmutableObject) Len() int { return len(o.fields) }
+
+// Keys returns the sorted list of keys of the ImmutableObject.
+func (o ImmutableObject) Keys() []string {
+	keys := make([]string, 0, len(o.fields))
+	for k := range o.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// With returns a copy of the ImmutableObject in which key is mapped to value.
+func (o ImmutableObject) With(key string, value Value) ImmutableObject {
+	fields := make(map[string]Value, len(o.fields)+1)
+	for k, v := range o.fields {
+		fields[k] = v
+	}
+	fields[key] = Freeze(value)
+	return ImmutableObject{fields}
+}
+
+// Without returns a copy of the ImmutableObject in which key is not mapped anymore.
+func (o ImmutableObject) Without(key string) ImmutableObject {
+	if _, ok := o.fields[key]; !ok {
+		return o
+	}
+	fields := make(map[string]Value, len(o.fields))
+	for k, v := range o.fields {
+		if k == key {
+			continue
+		}
+		fields[k] = v
+	}
+	return ImmutableObject{fields}
+}
+
+// Object returns a mutable copy of the ImmutableObject. Nested immutable
+// Values are left as is.
+func (o ImmutableObject) Object() Object {
+	p := NewObject()
+	for k, v := range o.fields {
+		p[k] = copyValue(v)
+	}
+	return p
+}
+
+// ImmutableList is a read-only counterpart of List.
+// Its update methods return a modified copy and leave the receiver untouched.
+type ImmutableList struct {
+	items []Value
+}
+
+// NewImmutableList returns an ImmutableList holding a copy of the given values.
+// Nested Objects and Lists are frozen as well.
+func NewImmutableList(values ...Value) ImmutableList {
+	items := make([]Value, len(values))
+	for i, v := range values {
+		items[i] = Freeze(v)
+	}
+	return ImmutableList{items}
+}
+
+func (l ImmutableList) discriminant() discriminant { return "particleui" }
+func (l ImmutableList) ValueType() string          { return "ImmutableList" }
+func (l ImmutableList) RawValue() Object {
+	o := l.List().RawValue()
+	o["typ"] = "ImmutableList"
+	return o
+}
+
+// Len returns the number of items of the ImmutableList.
+func (l ImmutableList) Len() int { return len(l.items) }
+
+// At returns the item stored at the given index, or nil if the index is out of
+// range.
+func (l ImmutableList) At(index int) Value {
+	if index < 0 || index >= len(l.items) {
+		return nil
+	}
+	return copyValue(l.items[index])
+}
+
+// Append returns a copy of the ImmutableList with values added at the end.
+func (l ImmutableList) Append(values ...Value) ImmutableList {
+	return l.InsertAt(len(l.items), values...)
+}
+
+// InsertAt returns a copy of the ImmutableList with values inserted at the
+// given index. An out of range index leaves the list unchanged.
+func (l ImmutableList) InsertAt(index int, values ...Value) ImmutableList {
+	if index < 0 || index > len(l.items) {
+		return l
+	}
+	items := make([]Value, 0, len(l.items)+len(values))
+	items = append(items, l.items[:index]...)
+	for _, v := range values {
+		items = append(items, Freeze(v))
+	}
+	items = append(items, l.items[index:]...)
+	return ImmutableList{items}
+}
+
+// SetAt returns a copy of the ImmutableList in which the item at the given
+// index is replaced. An out of range index leaves the list unchanged.
+func (l ImmutableList) SetAt(index int, value Value) ImmutableList {
+	if index < 0 || index >= len(l.items) {
+		return l
+	}
+	items := make([]Value, len(l.items))
+	copy(items, l.items)
+	items[index] = Freeze(value)
+	return ImmutableList{items}
+}
+
+// RemoveAt returns a copy of the ImmutableList without the item located at the
+// given index. An out of range index leaves the list unchanged.
+func (l ImmutableList) RemoveAt(index int) ImmutableList {
+	if index < 0 || index >= len(l.items) {
+		return l
+	}
+	items := make([]Value, 0, len(l.items)-1)
+	items = append(items, l.items[:index]...)
+	items = append(items, l.items[index+1:]...)
+	return ImmutableList{items}
+}
+
+// List returns a mutable copy of the ImmutableList. Nested immutable Values
+// are left as is.
+func (l ImmutableList) List() List {
+	items := make([]Value, len(l.items))
+	for i, v := range l.items {
+		items[i] = copyValue(v)
+	}
+	return List(items)
+}
+
+// Freeze returns an immutable version of a Value: Objects and Lists are turned
+// into ImmutableObjects and ImmutableLists recursively.
+// The other mutable Values, i.e. Commands, MutationRecords, Bytes and Objects
+// of another type, keep their type and are deep-copied instead. Immutable
+// containers only ever hand out copies of such Values, so that they cannot be
+// modified in place either.
+// Other Values are returned unchanged.
+func Freeze(v Value) Value {
+	switch t := v.(type) {
+	case Object:
+		if t.ValueType() != "Object" {
+			return copyValue(t)
+		}
+		return NewImmutableObject(t)
+	case List:
+		return NewImmutableList(t...)
+	default:
+		return copyValue(v)
+	}
+}
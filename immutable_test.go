@@ -0,0 +1,120 @@
+package ui
+
+import "testing"
+
+func TestImmutableCopyOnWrite(t *testing.T) {
+	o := NewObject()
+	o.Set("a", NewList(Number(1)))
+	io := NewImmutableObject(o)
+	if v, _ := io.Get("a"); v.ValueType() != "ImmutableList" {
+		t.Fatalf("nested List not frozen: %s", v.ValueType())
+	}
+	o.Set("a", String("changed"))
+	if v, _ := io.Get("a"); v.ValueType() != "ImmutableList" {
+		t.Error("ImmutableObject shares its entries with the source Object")
+	}
+
+	io2 := io.With("b", String("x")).Without("a")
+	if io.Len() != 1 || io2.Len() != 1 {
+		t.Errorf("got lengths %d and %d, want 1 and 1", io.Len(), io2.Len())
+	}
+
+	l := NewImmutableList(Number(1), Number(2))
+	l2 := l.Append(Number(3)).SetAt(0, Number(9)).RemoveAt(1).InsertAt(0, io)
+	if l.Len() != 2 || l.At(0) != Number(1) {
+		t.Errorf("receiver modified: %v", l)
+	}
+	if !Equal(l2, NewImmutableList(io, Number(9), Number(3))) {
+		t.Errorf("got %v", l2)
+	}
+}
+
+func TestFreezeMutableValues(t *testing.T) {
+	typed := NewObject().SetType("Custom")
+	typed.Set("n", Number(1))
+	values := map[string]Value{
+		"Command":        NewUICommand().Name("x"),
+		"MutationRecord": NewMutationRecord("ui", "text", String("a")),
+		"Bytes":          Bytes{1, 2, 3},
+		"TypedObject":    typed,
+	}
+	for name, v := range values {
+		io := NewImmutableObject(NewObject()).With("v", v)
+		l := NewImmutableList(v)
+
+		mutate(v)
+		got, _ := io.Get("v")
+		if Equal(got, v) {
+			t.Errorf("%s: ImmutableObject shares its entry with the caller", name)
+		}
+		if Equal(l.At(0), v) {
+			t.Errorf("%s: ImmutableList shares its item with the caller", name)
+		}
+
+		mutate(got)
+		mutate(l.At(0))
+		mutate(io.Object()["v"].(Value))
+		mutate(l.List()[0])
+		again, _ := io.Get("v")
+		if !Equal(again, l.At(0)) || Equal(again, got) {
+			t.Errorf("%s: entry modified in place through an accessor", name)
+		}
+		if again.ValueType() != v.ValueType() {
+			t.Errorf("%s: got type %s", name, again.ValueType())
+		}
+	}
+}
+
+// mutate modifies a mutable Value in place.
+func mutate(v Value) {
+	switch t := v.(type) {
+	case Bytes:
+		t[0]++
+	case Command:
+		Object(t)["mutated"] = Bool(true)
+	case MutationRecord:
+		Object(t)["mutated"] = Bool(true)
+	case Object:
+		t["mutated"] = Bool(true)
+	}
+}
+
+func TestImmutableRoundTrip(t *testing.T) {
+	obj := NewObject()
+	obj.Set("n", Number(3.5))
+	obj.Set("l", NewList(String("a"), Int(-2)))
+	tests := []struct {
+		name     string
+		v        Value
+		fromJSON Value // nil items are encoded as Null in JSON
+	}{
+		{"ImmutableObject", NewImmutableObject(obj), NewImmutableObject(obj)},
+		{"ImmutableList", NewImmutableList(String("x"), NewImmutableObject(obj)), NewImmutableList(String("x"), NewImmutableObject(obj))},
+		{"nil item", NewImmutableList(nil), NewImmutableList(Null)},
+	}
+	for _, test := range tests {
+		b, err := MarshalValue(test.v)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		r, err := UnmarshalValue(b)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !Equal(r, test.fromJSON) {
+			t.Errorf("%s: got %#v, want %#v", test.name, r, test.fromJSON)
+		}
+
+		b, err = MarshalBinaryValue(test.v)
+		if err != nil {
+			t.Fatalf("%s: binary: %v", test.name, err)
+		}
+		r, err = UnmarshalBinaryValue(b)
+		if err != nil {
+			t.Fatalf("%s: binary: %v", test.name, err)
+		}
+		if !Equal(r, test.v) {
+			t.Errorf("%s: binary: got %#v, want %#v", test.name, r, test.v)
+		}
+	}
+}
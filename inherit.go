@@ -0,0 +1,152 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+// Inheritance of properties
+//
+// An Element created with the EnablePropertyAutoInheritance option inherits
+// the inheritable properties of its ancestors: for each property, the value set
+// as inheritable by the nearest ancestor is stored among its Inherited
+// properties. An ancestor setting an inheritable value hence overrides it for
+// its own subtree.
+// Inherited values are kept up-to-date: they are pulled from the ancestors when
+// an Element is attached, and pushed down to the descendants each time an
+// inheritable property is set or deleted, once the corresponding MutationEvent
+// has been dispatched. A MutationEvent with the SourceInheritance
+// source is dispatched whenever the value of a property of a descendant
+// changes as a result.
+
+// PropertyInheritance returns whether an Element inherits the inheritable
+// properties of its ancestors.
+func PropertyInheritance(e *Element) bool {
+	v, ok := e.Get("internals", "propertyinheritance")
+	if !ok {
+		return false
+	}
+	b, ok := v.(Bool)
+	return ok && bool(b)
+}
+
+// setInherited stores an inherited property value and dispatches a
+// MutationEvent if it changes the value of the property.
+func (e *Element) setInherited(category string, propname string, value Value) {
+	ps, ok := e.Properties.Categories[category]
+	if !ok {
+		ps = newProperties()
+		e.Properties.Categories[category] = ps
+	}
+	old, existed := ps.Get(propname)
+	if value == nil {
+		delete(ps.Inherited, propname)
+	} else {
+		ps.Inherited[propname] = value
+	}
+	nv, ok := ps.Get(propname)
+	if existed == ok && Equal(old, nv) {
+		return
+	}
+	if !ok {
+		nv = Null
+	}
+	e.dispatchMutation(e.NewMutationEvent(category, propname, nv).WithOldValue(old, existed))
+}
+
+// propagateInheritable pushes an inheritable property value down the subtree
+// rooted at e, stopping at the Elements which define their own inheritable
+// value for the property.
+func propagateInheritable(e *Element, category string, propname string, value Value) {
+	if ps, ok := e.Properties.Categories[category]; ok {
+		if _, ok := ps.Inheritable[propname]; ok {
+			return
+		}
+	}
+	if PropertyInheritance(e) {
+		e.setInherited(category, propname, value)
+	}
+	for _, child := range e.Children.List {
+		propagateInheritable(child, category, propname, value)
+	}
+	for _, view := range e.InactiveViews {
+		for _, child := range view.Elements().List {
+			propagateInheritable(child, category, propname, value)
+		}
+	}
+}
+
+// propagateToDescendants is called once the MutationEvent of a change
+// affecting an inheritable property of e has been dispatched. The value in
+// effect for the children of e, nil if none, is pushed down its subtree.
+func (e *Element) propagateToDescendants(evt MutationEvent) {
+	category := evt.Type()
+	_, propname, ok := splitObservedKey(e.ID, evt.ObservedKey())
+	if !ok {
+		return
+	}
+	value := inheritableValue(e, category, propname)
+	defer e.propagation().push(cause{id: evt.ID()})()
+	defer e.enterMutationSource(SourceInheritance)()
+	for _, child := range e.Children.List {
+		propagateInheritable(child, category, propname, value)
+	}
+	for _, view := range e.InactiveViews {
+		for _, child := range view.Elements().List {
+			propagateInheritable(child, category, propname, value)
+		}
+	}
+}
+
+// inheritableValue returns the inheritable value of a property set by e or by
+// its nearest ancestor, or nil if there is none.
+func inheritableValue(e *Element, category string, propname string) Value {
+	for a := e; a != nil; a = a.Parent {
+		if ps, ok := a.Properties.Categories[category]; ok {
+			if v, ok := ps.Inheritable[propname]; ok {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// inheritedValues returns, per category, the inheritable property values in
+// effect for the children of e.
+func inheritedValues(e *Element) map[string]map[string]Value {
+	values := make(map[string]map[string]Value)
+	for a := e; a != nil; a = a.Parent {
+		for category, ps := range a.Properties.Categories {
+			for propname, v := range ps.Inheritable {
+				c, ok := values[category]
+				if !ok {
+					c = make(map[string]Value)
+					values[category] = c
+				}
+				if _, ok := c[propname]; !ok {
+					c[propname] = v
+				}
+			}
+		}
+	}
+	return values
+}
+
+// inherit updates the inherited properties of an Element being attached to a
+// new parent. Inherited values which are no longer provided by the new
+// ancestors are removed.
+func inherit(parent *Element, child *Element) {
+	if !PropertyInheritance(child) {
+		return
+	}
+	defer child.enterMutationSource(SourceInheritance)()
+	values := inheritedValues(parent)
+	for category, ps := range child.Properties.Categories {
+		for propname := range ps.Inherited {
+			if _, ok := values[category][propname]; !ok {
+				child.setInherited(category, propname, nil)
+			}
+		}
+	}
+	for category, props := range values {
+		for propname, v := range props {
+			child.setInherited(category, propname, v)
+		}
+	}
+}
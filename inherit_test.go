@@ -0,0 +1,104 @@
+package ui
+
+import "testing"
+
+// inheritanceTree returns an app root with a chain of descendants created with
+// the EnablePropertyAutoInheritance option.
+func inheritanceTree(storeid string, ids ...string) (*Element, []*Element) {
+	s, thing := newTestStore(storeid)
+	root := s.NewAppRoot(storeid + "-root")
+	parent := root
+	var elements []*Element
+	for _, id := range ids {
+		e := thing(id, storeid+"-"+id, EnablePropertyAutoInheritance())
+		parent.AppendChild(e)
+		elements = append(elements, e)
+		parent = e
+	}
+	return root, elements
+}
+
+func TestInheritablePropagation(t *testing.T) {
+	root, el := inheritanceTree("inheritstore", "a", "b")
+	a, b := el[0], el[1]
+	var got []MutationEvent
+	b.Watch("css", "theme", b, NewMutationHandler(func(evt MutationEvent) bool {
+		got = append(got, evt)
+		return false
+	}))
+
+	root.Set("css", "theme", String("dark"), true)
+	if v, _ := b.Get("css", "theme"); v != String("dark") {
+		t.Fatalf("got %v, want dark", v)
+	}
+	if len(got) != 1 || got[0].Source() != SourceInheritance || got[0].ParentID() == "" {
+		t.Fatalf("got %v", got)
+	}
+
+	a.Set("css", "theme", String("light"), true)
+	root.Set("css", "theme", String("blue"), true)
+	if v, _ := b.Get("css", "theme"); v != String("light") || len(got) != 2 {
+		t.Errorf("got %v after %d events, want light after 2", v, len(got))
+	}
+
+	c := NewElement("c", "inheritstore-c", "test")
+	c.Set("internals", "propertyinheritance", Bool(true))
+	b.AppendChild(c)
+	if v, _ := c.Get("css", "theme"); v != String("light") {
+		t.Errorf("Element attached later: got %v, want light", v)
+	}
+
+	b.Set("css", "theme", String("local"))
+	a.Set("css", "theme", String("red"), true)
+	if v, _ := b.Get("css", "theme"); v != String("local") {
+		t.Errorf("got %v, want the local value", v)
+	}
+	if v, _ := c.Get("css", "theme"); v != String("red") {
+		t.Errorf("got %v, want red", v)
+	}
+}
+
+func TestInheritableDeletion(t *testing.T) {
+	root, el := inheritanceTree("inheritdelstore", "a", "b")
+	a, b := el[0], el[1]
+	root.Set("ui", "theme", String("dark"), true)
+	a.Set("ui", "theme", String("light"), true)
+
+	a.Delete("ui", "theme")
+	if v, _ := b.Get("ui", "theme"); v != String("dark") {
+		t.Errorf("got %v, want the value of the next ancestor", v)
+	}
+	root.Delete("ui", "theme")
+	if v, ok := b.Get("ui", "theme"); ok {
+		t.Errorf("got %v, want no value", v)
+	}
+	if v, ok := a.Get("ui", "theme"); ok {
+		t.Errorf("got %v, want no value", v)
+	}
+}
+
+func TestInheritablePropagationInTx(t *testing.T) {
+	root, el := inheritanceTree("inherittxstore", "a")
+	a := el[0]
+	var order []string
+	record := func(name string) *MutationHandler {
+		return NewMutationHandler(func(evt MutationEvent) bool {
+			order = append(order, name+"="+string(evt.NewValue().(String)))
+			return false
+		})
+	}
+	root.Watch("ui", "theme", root, record("root"))
+	a.Watch("ui", "theme", a, record("a"))
+
+	err := root.Batch(func(tx *Tx) error {
+		tx.Set(root, "ui", "theme", String("dark"), true)
+		return tx.Set(root, "ui", "theme", String("light"), true)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"root=light", "a=light"}
+	if len(order) != len(want) || order[0] != want[0] || order[1] != want[1] {
+		t.Errorf("got %v, want %v", order, want)
+	}
+}
@@ -0,0 +1,68 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrMutationVetoed may be returned by an interceptor to reject a change
+	// without further explanation.
+	ErrMutationVetoed = errors.New("mutation vetoed")
+)
+
+type interceptor struct {
+	fn func(old Value, new Value) (Value, error)
+}
+
+// Intercept registers a function which is called each time the named property
+// is about to be set, before the value is validated, persisted and stored.
+// It receives the current value of the property, nil if it is not set, and
+// the new one. It returns the value to be stored instead, which allows to
+// clamp or coerce values, or an error to veto the change. A vetoed change is
+// not applied and the error is returned to the caller of the setter.
+// Interceptors run in registration order, each one receiving the value
+// returned by the previous one.
+// Cancelling the returned Subscription removes the interceptor.
+func (e *Element) Intercept(category string, propname string, fn func(old Value, new Value) (Value, error)) *Subscription {
+	if e.interceptors == nil {
+		e.interceptors = make(map[string][]*interceptor)
+	}
+	key := category + "/" + propname
+	i := &interceptor{fn}
+	e.interceptors[key] = append(e.interceptors[key], i)
+	return newSubscription(e, e, e.ID+"/"+key, i, func() {
+		l := e.interceptors[key]
+		for k, v := range l {
+			if v != i {
+				continue
+			}
+			nl := make([]*interceptor, 0, len(l)-1)
+			nl = append(nl, l[:k]...)
+			e.interceptors[key] = append(nl, l[k+1:]...)
+			break
+		}
+		if len(e.interceptors[key]) == 0 {
+			delete(e.interceptors, key)
+		}
+	})
+}
+
+// intercept passes a new property value through the interceptors registered
+// for the property.
+func (e *Element) intercept(category string, propname string, value Value) (Value, error) {
+	l, ok := e.interceptors[category+"/"+propname]
+	if !ok {
+		return value, nil
+	}
+	old, _ := e.Properties.Get(category, propname)
+	for _, i := range l {
+		v, err := i.fn(old, value)
+		if err != nil {
+			return nil, fmt.Errorf("Element %s: %s/%s: %w", e.ID, category, propname, err)
+		}
+		value = v
+	}
+	return value, nil
+}
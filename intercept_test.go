@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+)
+
+// clamp is an interceptor limiting a Number to 10 and vetoing negative ones.
+func clamp(old Value, new Value) (Value, error) {
+	n, ok := new.(Number)
+	if !ok || n < 0 {
+		return nil, ErrMutationVetoed
+	}
+	if n > 10 {
+		return Number(10), nil
+	}
+	return new, nil
+}
+
+func TestIntercept(t *testing.T) {
+	setters := []struct {
+		name string
+		set  func(e *Element, v Value) error
+	}{
+		{"Set", func(e *Element, v Value) error { return e.Set("data", "v", v) }},
+		{"Tx.Set", func(e *Element, v Value) error {
+			return e.Batch(func(tx *Tx) error { return tx.Set(e, "data", "v", v) })
+		}},
+	}
+	for _, setter := range setters {
+		_, thing := newTestStore("intercept" + setter.name)
+		e := thing("e", "intercept")
+		var dispatched []Value
+		e.Watch("data", "v", e, NewMutationHandler(func(evt MutationEvent) bool {
+			dispatched = append(dispatched, evt.NewValue())
+			return false
+		}))
+		e.Intercept("data", "v", clamp)
+
+		// rewrite
+		if err := setter.set(e, Number(15)); err != nil {
+			t.Fatalf("%s: %v", setter.name, err)
+		}
+		if v, _ := e.GetData("v"); !Equal(v, Number(10)) {
+			t.Errorf("%s: got %v stored, want the rewritten value 10", setter.name, v)
+		}
+
+		// veto
+		if err := setter.set(e, Number(-1)); !errors.Is(err, ErrMutationVetoed) {
+			t.Errorf("%s: got %v, want ErrMutationVetoed", setter.name, err)
+		}
+		if v, _ := e.GetData("v"); !Equal(v, Number(10)) {
+			t.Errorf("%s: vetoed value stored: got %v", setter.name, v)
+		}
+		if len(dispatched) != 1 || !Equal(dispatched[0], Number(10)) {
+			t.Errorf("%s: got %v dispatched, want [10]", setter.name, dispatched)
+		}
+	}
+}
+
+func TestInterceptChain(t *testing.T) {
+	_, thing := newTestStore("interceptchain")
+	e := thing("e", "interceptchain")
+	var olds []Value
+	e.Intercept("data", "v", func(old Value, new Value) (Value, error) {
+		olds = append(olds, old)
+		return new.(Number) * 2, nil
+	})
+	sub := e.Intercept("data", "v", func(old Value, new Value) (Value, error) {
+		return new.(Number) + 1, nil
+	})
+
+	e.SetData("v", Number(1))
+	e.SetData("v", Number(2))
+	if v, _ := e.GetData("v"); !Equal(v, Number(5)) {
+		t.Errorf("got %v, want interceptors to run in registration order", v)
+	}
+	if len(olds) != 2 || olds[0] != nil || !Equal(olds[1], Number(3)) {
+		t.Errorf("got old values %v, want [<nil> 3]", olds)
+	}
+
+	sub.Cancel()
+	e.SetData("v", Number(2))
+	if v, _ := e.GetData("v"); !Equal(v, Number(4)) {
+		t.Errorf("cancelled interceptor still running: got %v", v)
+	}
+}
@@ -0,0 +1,173 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+import (
+	"log"
+	"sort"
+)
+
+// SetChildrenKeyed updates the children of the Element so that they correspond,
+// in order, to the given list of keys. The key of a child is stored in its
+// ("internals","childkey") property.
+// Children whose key is still listed are kept, along with their state; children
+// whose key is no longer listed are removed. For each new key, build is called
+// to create the corresponding child.
+//
+// The NativeElement receives a minimal set of operations: new children replace
+// removed ones as long as there are some, then children which are out of order
+// are moved, the largest subset of them which is already in order staying in
+// place. Moved children are not detached.
+//
+// Children created otherwise than via SetChildrenKeyed have no key and are
+// removed. Duplicate keys are ignored.
+func (e *Element) SetChildrenKeyed(keys []string, build func(key string) *Element) *Element {
+	current := make(map[*Element]bool, len(e.Children.List))
+	byKey := make(map[string]*Element, len(e.Children.List))
+	for _, child := range e.Children.List {
+		current[child] = true
+		if key, ok := childKey(child); ok {
+			byKey[key] = child
+		}
+	}
+
+	desired := make([]*Element, 0, len(keys))
+	wanted := make(map[*Element]bool, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			log.Printf("Element %s: duplicate child key %s", e.ID, key)
+			continue
+		}
+		seen[key] = true
+		child, ok := byKey[key]
+		if !ok {
+			child = build(key)
+			if child == nil {
+				log.Printf("Element %s: no child built for key %s", e.ID, key)
+				continue
+			}
+			if current[child] || wanted[child] {
+				log.Printf("Element %s: child built for key %s is already in use", e.ID, key)
+				continue
+			}
+			child.Set("internals", "childkey", String(key))
+		}
+		desired = append(desired, child)
+		wanted[child] = true
+	}
+
+	// New children replace the removed ones, in order. The remaining removed
+	// children are removed.
+	var added []*Element
+	for _, child := range desired {
+		if !current[child] {
+			added = append(added, child)
+		}
+	}
+	old := make([]*Element, len(e.Children.List))
+	copy(old, e.Children.List)
+	for _, child := range old {
+		if wanted[child] {
+			continue
+		}
+		if len(added) > 0 {
+			e.replaceChild(child, added[0])
+			current[added[0]] = true
+			added = added[1:]
+		} else {
+			e.removeChild(child)
+		}
+		delete(current, child)
+	}
+
+	// The children in place are those forming the longest sequence already in
+	// the desired order.
+	position := make(map[*Element]int, len(desired))
+	for i, child := range desired {
+		position[child] = i
+	}
+	sequence := make([]int, len(e.Children.List))
+	for i, child := range e.Children.List {
+		sequence[i] = position[child]
+	}
+	inplace := make(map[*Element]bool, len(sequence))
+	for _, i := range longestIncreasingSubsequence(sequence) {
+		inplace[desired[i]] = true
+	}
+
+	// Moving from the end, each child is positioned right before its successor.
+	var next *Element
+	for i := len(desired) - 1; i >= 0; i-- {
+		child := desired[i]
+		if !inplace[child] {
+			if current[child] {
+				e.moveChild(child, next)
+			} else if next == nil {
+				e.appendChild(child)
+			} else {
+				index, _ := e.hasChild(next)
+				e.insertChild(child, index)
+			}
+		}
+		next = child
+	}
+	return e
+}
+
+// childKey returns the key of a child created via SetChildrenKeyed.
+func childKey(e *Element) (string, bool) {
+	v, ok := e.Get("internals", "childkey")
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(String)
+	return string(s), ok
+}
+
+// moveChild moves a child of the Element right before another one, or at the
+// end if next is nil. The child remains attached.
+func (e *Element) moveChild(child *Element, next *Element) {
+	if e.Native != nil {
+		if next == nil {
+			e.Native.AppendChild(child)
+		} else {
+			index, _ := e.hasChild(next)
+			e.Native.InsertChild(child, index)
+		}
+	}
+	e.Children.Remove(child)
+	if next == nil {
+		e.Children.InsertLast(child)
+		return
+	}
+	index, _ := e.hasChild(next)
+	e.Children.Insert(child, index)
+}
+
+// longestIncreasingSubsequence returns the values of a longest strictly
+// increasing subsequence of s.
+func longestIncreasingSubsequence(s []int) []int {
+	tails := make([]int, 0, len(s)) // index in s of the tail of the best subsequence of each length
+	prev := make([]int, len(s))
+	for i, v := range s {
+		k := sort.Search(len(tails), func(j int) bool { return s[tails[j]] >= v })
+		if k > 0 {
+			prev[i] = tails[k-1]
+		} else {
+			prev[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	res := make([]int, len(tails))
+	if len(tails) == 0 {
+		return res
+	}
+	for k, i := len(tails)-1, tails[len(tails)-1]; k >= 0; k, i = k-1, prev[i] {
+		res[k] = s[i]
+	}
+	return res
+}
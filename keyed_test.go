@@ -0,0 +1,150 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recordingNative is a NativeElement which keeps track of its children and of
+// the operations it receives.
+type recordingNative struct {
+	children []*Element
+	ops      []string
+}
+
+func (n *recordingNative) index(child *Element) int {
+	for i, c := range n.children {
+		if c == child {
+			return i
+		}
+	}
+	return -1
+}
+
+func (n *recordingNative) remove(child *Element) {
+	if i := n.index(child); i >= 0 {
+		n.children = append(n.children[:i:i], n.children[i+1:]...)
+	}
+}
+
+func (n *recordingNative) AppendChild(child *Element) {
+	n.remove(child)
+	n.children = append(n.children, child)
+	n.ops = append(n.ops, "append "+child.Name)
+}
+
+func (n *recordingNative) PrependChild(child *Element) {
+	n.remove(child)
+	n.children = append([]*Element{child}, n.children...)
+	n.ops = append(n.ops, "prepend "+child.Name)
+}
+
+func (n *recordingNative) InsertChild(child *Element, index int) {
+	next := n.children[index]
+	n.remove(child)
+	i := n.index(next)
+	n.children = append(n.children[:i:i], append([]*Element{child}, n.children[i:]...)...)
+	n.ops = append(n.ops, "insert "+child.Name)
+}
+
+func (n *recordingNative) ReplaceChild(old *Element, new *Element) {
+	n.children[n.index(old)] = new
+	n.ops = append(n.ops, "replace "+old.Name+" "+new.Name)
+}
+
+func (n *recordingNative) RemoveChild(child *Element) {
+	n.remove(child)
+	n.ops = append(n.ops, "remove "+child.Name)
+}
+
+func TestSetChildrenKeyed(t *testing.T) {
+	s, thing := newTestStore("keyedstore")
+	root := s.NewAppRoot("keyed-root")
+	list := thing("list", "keyed-list")
+	native := &recordingNative{}
+	list.Native = native
+	root.AppendChild(list)
+
+	built := make(map[string]*Element)
+	build := func(key string) *Element {
+		e := thing(key, NewIDgenerator(int64(len(built)))())
+		built[key] = e
+		return e
+	}
+	names := func(children []*Element) string {
+		var b strings.Builder
+		for _, c := range children {
+			b.WriteString(c.Name)
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		keys string
+		ops  []string
+	}{
+		{"abcde", []string{"append e", "insert d", "insert c", "insert b", "insert a"}},
+		{"bcdea", []string{"append a"}},
+		{"ebxda", []string{"replace c x", "insert e"}},
+		{"ebxd", []string{"remove a"}},
+		{"debx", []string{"insert d"}},
+		{"", []string{"remove d", "remove e", "remove b", "remove x"}},
+	}
+	for _, test := range tests {
+		native.ops = nil
+		list.SetChildrenKeyed(strings.Split(test.keys, ""), build)
+		if got := names(list.Children.List); got != test.keys {
+			t.Fatalf("%s: got children %s", test.keys, got)
+		}
+		if got := names(native.children); got != test.keys {
+			t.Fatalf("%s: got native children %s", test.keys, got)
+		}
+		if !reflect.DeepEqual(native.ops, test.ops) {
+			t.Errorf("%s: got %q, want %q", test.keys, native.ops, test.ops)
+		}
+	}
+}
+
+func TestSetChildrenKeyedKeepsState(t *testing.T) {
+	s, thing := newTestStore("keyedstatestore")
+	root := s.NewAppRoot("keyedstate-root")
+	list := thing("list", "keyedstate-list")
+	root.AppendChild(list)
+	build := func(key string) *Element { return thing(key, "keyedstate-"+key) }
+
+	list.SetChildrenKeyed([]string{"a", "b", "c"}, build)
+	a := list.Children.List[0]
+	a.SetData("state", String("kept"))
+	var unmounted bool
+	a.OnUnmount(NewMutationHandler(func(evt MutationEvent) bool {
+		unmounted = true
+		return false
+	}))
+
+	list.SetChildrenKeyed([]string{"c", "b", "a"}, build)
+	if list.Children.List[2] != a || unmounted || !a.Mounted() {
+		t.Fatal("moved child was recreated or unmounted")
+	}
+	if v, _ := a.GetData("state"); v != String("kept") {
+		t.Errorf("got %v, want the state of the moved child", v)
+	}
+}
+
+func TestLongestIncreasingSubsequence(t *testing.T) {
+	tests := []struct {
+		in, want []int
+	}{
+		{[]int{}, []int{}},
+		{[]int{3}, []int{3}},
+		{[]int{3, 1, 2}, []int{1, 2}},
+		{[]int{5, 1, 6, 2, 7, 3}, []int{1, 2, 3}},
+		{[]int{4, 3, 2, 1}, []int{1}},
+		{[]int{0, 1, 2}, []int{0, 1, 2}},
+	}
+	for _, test := range tests {
+		if got := longestIncreasingSubsequence(test.in); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %v, want %v", test.in, got, test.want)
+		}
+	}
+}
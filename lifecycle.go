@@ -0,0 +1,118 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+// Lifecycle
+//
+// The lifecycle of an Element is reflected by properties of its "event"
+// category which can be watched via the hooks below:
+//
+//   - "attached" is set to true when the Element is attached to a parent, and to
+//     false when it is detached from it.
+//   - "mounted" is set to true when the Element becomes part of the tree of an app
+//     root, and to false when it stops being part of it.
+//   - "unmounting" is set to true right before an Element is unmounted, and back
+//     to false once it has been.
+//   - "viewactivated" and "viewdeactivated" hold the name of the last view of a
+//     ViewElement that was respectively activated and deactivated.
+//   - "disposed" is set to true when the Element is removed from its
+//     ElementStore.
+//
+// When a subtree is mounted or unmounted, children are notified before their
+// parents.
+
+// onEvent registers a MutationHandler for the lifecycle property propname
+// which is only called when match returns true for the new value.
+func (e *Element) onEvent(propname string, match func(Value) bool, h *MutationHandler) *Subscription {
+	nh := NewMutationHandler(func(evt MutationEvent) bool {
+		if !match(evt.NewValue()) {
+			return false
+		}
+		return h.Handle(evt)
+	})
+	return e.Watch("event", propname, e, nh)
+}
+
+func isTrue(v Value) bool {
+	b, ok := v.(Bool)
+	return ok && bool(b)
+}
+
+func isFalse(v Value) bool {
+	b, ok := v.(Bool)
+	return ok && !bool(b)
+}
+
+func isString(v Value) bool {
+	_, ok := v.(String)
+	return ok
+}
+
+// OnMount registers a MutationHandler called each time the Element is mounted.
+func (e *Element) OnMount(h *MutationHandler) *Subscription {
+	return e.onEvent("mounted", isTrue, h)
+}
+
+// OnUnmount registers a MutationHandler called each time the Element is
+// unmounted, i.e. when it or one of its ancestors is detached from the tree of
+// an app root. Descendants are unmounted before their ancestors.
+func (e *Element) OnUnmount(h *MutationHandler) *Subscription {
+	return e.onEvent("mounted", isFalse, h)
+}
+
+// OnBeforeUnmount registers a MutationHandler called right before the Element
+// is unmounted, while it is still part of the tree of an app root.
+// Descendants are notified before their ancestors.
+func (e *Element) OnBeforeUnmount(h *MutationHandler) *Subscription {
+	return e.onEvent("unmounting", isTrue, h)
+}
+
+// OnAttach registers a MutationHandler called each time the Element is
+// attached to a parent.
+func (e *Element) OnAttach(h *MutationHandler) *Subscription {
+	return e.onEvent("attached", isTrue, h)
+}
+
+// OnDetach registers a MutationHandler called each time the Element is
+// detached from its parent.
+func (e *Element) OnDetach(h *MutationHandler) *Subscription {
+	return e.onEvent("attached", isFalse, h)
+}
+
+// OnViewActivated registers a MutationHandler called each time a view of the
+// ViewElement is activated. The new value of the MutationEvent is the name of
+// the view.
+func (v ViewElement) OnViewActivated(h *MutationHandler) *Subscription {
+	return v.Element().onEvent("viewactivated", isString, h)
+}
+
+// OnViewDeactivated registers a MutationHandler called each time the active
+// view of the ViewElement is replaced. The new value of the MutationEvent is the
+// name of the deactivated view.
+func (v ViewElement) OnViewDeactivated(h *MutationHandler) *Subscription {
+	return v.Element().onEvent("viewdeactivated", isString, h)
+}
+
+// OnDispose registers a MutationHandler called when the Element is removed
+// from its ElementStore, before its Subscriptions are cancelled.
+func (e *Element) OnDispose(h *MutationHandler) *Subscription {
+	return e.onEvent("disposed", isTrue, h)
+}
+
+// beforeUnmount notifies the Elements of the subtree rooted at e that they are
+// about to be unmounted, children first.
+func beforeUnmount(e *Element) {
+	for _, child := range e.Children.List {
+		beforeUnmount(child)
+	}
+	e.Set("event", "unmounting", Bool(true))
+}
+
+// unmount notifies the Elements of the subtree rooted at e that they have been
+// unmounted, children first.
+func unmount(e *Element) {
+	for _, child := range e.Children.List {
+		unmount(child)
+	}
+	e.Set("event", "mounted", Bool(false))
+	e.Set("event", "unmounting", Bool(false))
+}
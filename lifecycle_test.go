@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLifecycleOrder(t *testing.T) {
+	s, thing := newTestStore("lifecyclestore")
+	root := s.NewAppRoot("lifecycle-root")
+
+	var got []string
+	record := func(label string) *MutationHandler { return recorder(&got, label) }
+	hook := func(e *Element) *Element {
+		e.OnAttach(record("attach:" + e.ID))
+		e.OnMount(record("mount:" + e.ID))
+		e.OnBeforeUnmount(record("beforeunmount:" + e.ID))
+		e.OnDetach(record("detach:" + e.ID))
+		e.OnUnmount(record("unmount:" + e.ID))
+		return e
+	}
+	a := hook(thing("a", "a"))
+	b := hook(thing("b", "b"))
+	c := hook(thing("c", "c"))
+	a.AppendChild(b)
+	b.AppendChild(c)
+	if c.Mounted() {
+		t.Fatal("Element mounted without being attached to the app root")
+	}
+
+	tests := []struct {
+		name string
+		fn   func()
+		want []string
+	}{
+		{"mount", func() { root.AppendChild(a) }, []string{"mount:c", "mount:b", "attach:a", "mount:a"}},
+		{"unmount", func() { root.removeChild(a) }, []string{
+			"beforeunmount:c", "beforeunmount:b", "beforeunmount:a",
+			"detach:a",
+			"unmount:c", "unmount:b", "unmount:a",
+		}},
+	}
+	for _, test := range tests {
+		got = nil
+		test.fn()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+	if a.Mounted() || c.Mounted() {
+		t.Error("Elements still mounted after removal")
+	}
+
+	got = nil
+	a.OnDispose(record("dispose"))
+	s.RemoveByID(a.ID)
+	if !reflect.DeepEqual(got, []string{"dispose"}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestViewLifecycle(t *testing.T) {
+	s, thing := newTestStore("viewlifecyclestore")
+	root := s.NewAppRoot("viewlifecycle-root")
+
+	var got []string
+	record := func(label string) *MutationHandler { return recorder(&got, label) }
+	x, y, z := thing("x", "vx"), thing("y", "vy"), thing("z", "vz")
+	x.OnUnmount(record("unmount:vx"))
+	y.OnUnmount(record("unmount:vy"))
+	z.OnMount(record("mount:vz"))
+	v := NewViewElement(thing("v", "v"), NewView("one", x, y), NewView("two", z))
+	v.OnViewDeactivated(record("deactivated"))
+	v.OnViewActivated(record("activated"))
+	root.AppendChild(v.Element())
+	v.ActivateView("one")
+	if !x.Mounted() || z.Mounted() {
+		t.Fatal("wrong mount state after activating the first view")
+	}
+
+	got = nil
+	v.ActivateView("two")
+	want := []string{"unmount:vx", "unmount:vy", "deactivated", "mount:vz", "activated"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if x.Mounted() || y.Mounted() || !z.Mounted() {
+		t.Error("wrong mount state after switching views")
+	}
+}
@@ -2,16 +2,200 @@
 package ui
 
 import (
-	//"log"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
 	"strings"
+	"sync/atomic"
 )
 
+var (
+	ErrMutationCycle       = errors.New("mutation cycle detected")
+	ErrMaxPropagationDepth = errors.New("maximum mutation propagation depth exceeded")
+	ErrInvalidPattern      = errors.New("malformed mutation pattern")
+)
+
+// MaxPropagationDepth is the maximum number of nested MutationEvent dispatches,
+// i.e. of mutation handlers setting properties whose handlers set properties
+// in turn, etc. Past this depth, the propagation is interrupted.
+var MaxPropagationDepth = 256
+
+// propagation tracks the chain of MutationEvents being dispatched.
+// A MutationEvent re-entering the chain with the same observed key and an
+// equal value denotes a cycle: the propagation would repeat itself
+// indefinitely, so it is interrupted.
+//
+// Each ElementStore has its own propagation, so that distinct stores may be
+// mutated from distinct goroutines. The mutations of a given store must still
+// happen on a single goroutine, typically the UI thread (see Do).
+// Elements that do not belong to any store share a package-level propagation.
+type propagation struct {
+	chain  []MutationEvent
+	errs   []error // errors that interrupted the ongoing propagation, in order of occurrence
+	causes []cause // events being handled and source scopes, innermost last
+}
+
+// cause is either an event being handled, identified by its ID, or a scope
+// within which mutations originate from a given source.
+type cause struct {
+	id     string
+	source MutationSource
+}
+
+var inflight = &propagation{}
+
+// propagation returns the tracker of the mutations of the Element: the one of
+// its ElementStore if any. The global Element of a store, which is not
+// referencing it, shares the tracker of the store.
+func (e *Element) propagation() *propagation {
+	if e == nil {
+		return inflight
+	}
+	s := e.ElementStore
+	if s == nil {
+		if gs, ok := Stores.Get(e.ID); ok && gs.Global == e {
+			s = gs
+		}
+	}
+	if s != nil && s.propagation != nil {
+		return s.propagation
+	}
+	return inflight
+}
+
+func (p *propagation) enter(evt MutationEvent) error {
+	if len(p.chain) >= MaxPropagationDepth {
+		return p.fail(ErrMaxPropagationDepth, evt)
+	}
+	for _, e := range p.chain {
+		if e.ObservedKey() == evt.ObservedKey() && Equal(e.NewValue(), evt.NewValue()) {
+			return p.fail(ErrMutationCycle, evt)
+		}
+	}
+	p.chain = append(p.chain, evt)
+	p.causes = append(p.causes, cause{id: evt.ID()})
+	return nil
+}
+
+func (p *propagation) exit() {
+	p.chain = p.chain[:len(p.chain)-1]
+	p.causes = p.causes[:len(p.causes)-1]
+}
+
+// origin returns the source and causal parent of a mutation occurring now.
+// The source is the one of the innermost scope if no event is being handled
+// within it. Otherwise, the mutation is caused by the handling of that event.
+func (p *propagation) origin() (MutationSource, string) {
+	source := SourceProgram
+	if n := len(p.causes); n > 0 && p.causes[n-1].id == "" {
+		source = p.causes[n-1].source
+	}
+	for i := len(p.causes) - 1; i >= 0; i-- {
+		if id := p.causes[i].id; id != "" {
+			return source, id
+		}
+	}
+	return source, ""
+}
+
+func (p *propagation) push(c cause) func() {
+	p.causes = append(p.causes, c)
+	n := len(p.causes)
+	return func() {
+		p.causes = p.causes[:n-1]
+	}
+}
+
+// MutationSource describes where a mutation originates from.
+type MutationSource string
+
+const (
+	SourceProgram     MutationSource = "program"     // direct call to a setter, or mutation made by a handler
+	SourceUserInput   MutationSource = "userinput"   // value changed via the UI, see SyncUISetData
+	SourceCommand     MutationSource = "command"     // Command execution or replay
+	SourceLoad        MutationSource = "load"        // properties loaded from persistent storage
+	SourceInheritance MutationSource = "inheritance" // inheritable property propagated from an ancestor
+	SourceRouter      MutationSource = "router"
+	SourceComputed    MutationSource = "computed" // recomputation of a computed property
+)
+
+// EnterMutationSource marks the mutations of the Elements of the store that
+// occur until the returned function is called as originating from the given
+// source. The mutations made by the handlers of these mutations are not
+// concerned: they have the SourceProgram source and the mutation that caused
+// them as parent.
+//
+//	defer e.ElementStore.EnterMutationSource(ui.SourceLoad)()
+func (e *ElementStore) EnterMutationSource(src MutationSource) (exit func()) {
+	return e.propagation.push(cause{source: src})
+}
+
+// EnterMutationSource is the equivalent of ElementStore.EnterMutationSource for
+// the Elements which do not belong to any ElementStore.
+func EnterMutationSource(src MutationSource) (exit func()) {
+	return inflight.push(cause{source: src})
+}
+
+// enterMutationSource marks the mutations occurring within the ElementStore of
+// the Element as originating from the given source.
+func (e *Element) enterMutationSource(src MutationSource) (exit func()) {
+	return e.propagation().push(cause{source: src})
+}
+
+// enterEvent marks the mutations that occur until the returned function is
+// called as caused by the handling of a UI Event by the Element.
+func (e *Element) enterEvent(evt Event) (exit func()) {
+	return e.propagation().push(cause{id: newEventID("e") + ":" + evt.Type()})
+}
+
+var eventCounter uint64
+
+func newEventID(prefix string) string {
+	return prefix + strconv.FormatUint(atomic.AddUint64(&eventCounter, 1), 10)
+}
+
+func (p *propagation) fail(reason error, evt MutationEvent) error {
+	keys := make([]string, 0, len(p.chain)+1)
+	for _, e := range p.chain {
+		keys = append(keys, e.ObservedKey())
+	}
+	keys = append(keys, evt.ObservedKey())
+	err := fmt.Errorf("%w: %s", reason, strings.Join(keys, " -> "))
+	p.errs = append(p.errs, err)
+	log.Print(err)
+	return err
+}
+
+// errorMark returns a mark to be passed to propagationError by a function about
+// to dispatch MutationEvents.
+func (p *propagation) errorMark() int {
+	return len(p.errs)
+}
+
+// propagationError returns the first error that interrupted the propagation of
+// the MutationEvents dispatched since the mark was taken, if any. Errors raised
+// by other, e.g. sibling, propagations beforehand are not reported.
+// Once the outermost dispatch has returned, errors are discarded.
+func (p *propagation) propagationError(mark int) error {
+	var err error
+	if len(p.errs) > mark {
+		err = p.errs[mark]
+	}
+	if len(p.chain) == 0 {
+		p.errs = nil
+	}
+	return err
+}
+
 type MutationCallbacks struct {
-	list map[string]*mutationHandlers
+	list     map[string]*mutationHandlers
+	patterns map[string]*mutationHandlers
 }
 
 func NewMutationCallbacks() *MutationCallbacks {
-	return &MutationCallbacks{make(map[string]*mutationHandlers, 0)}
+	return &MutationCallbacks{make(map[string]*mutationHandlers, 0), make(map[string]*mutationHandlers, 0)}
 }
 
 func (m *MutationCallbacks) Add(key string, h *MutationHandler) *MutationCallbacks {
@@ -34,24 +218,180 @@ func (m *MutationCallbacks) Remove(key string, h *MutationHandler) *MutationCall
 	return m
 }
 
+// AddPattern registers a MutationHandler for every observed key matching the
+// pattern. See MatchKey for the pattern syntax.
+func (m *MutationCallbacks) AddPattern(pattern string, h *MutationHandler) *MutationCallbacks {
+	mhs, ok := m.patterns[pattern]
+	if !ok {
+		mhs = newMutationHandlers()
+		m.patterns[pattern] = mhs
+	}
+	mhs.Add(h)
+	return m
+}
+
+func (m *MutationCallbacks) RemovePattern(pattern string, h *MutationHandler) *MutationCallbacks {
+	mhs, ok := m.patterns[pattern]
+	if !ok {
+		return m
+	}
+	mhs.Remove(h)
+	if len(mhs.list) == 0 {
+		delete(m.patterns, pattern)
+	}
+	return m
+}
+
+// registered returns whether h is registered in the given map of handlers.
+func registered(handlers map[string]*mutationHandlers, key string, h *MutationHandler) bool {
+	mhs, ok := handlers[key]
+	if !ok {
+		return false
+	}
+	for _, v := range mhs.list {
+		if v == h {
+			return true
+		}
+	}
+	return false
+}
+
+// DispatchEvent calls the handlers registered for the observed key of the event.
+// When the callbacks are those of the Element at the origin of the event, the
+// pattern handlers registered on its ancestors and on its ElementStore are
+// called as well.
+//
+// Dispatches are tracked so that a cyclic or overly deep propagation of
+// mutations is interrupted instead of overflowing the stack. The resulting
+// error is returned by the Element method that triggered the propagation.
 func (m *MutationCallbacks) DispatchEvent(evt MutationEvent) {
+	p := evt.Origin().propagation()
+	if err := p.enter(evt); err != nil {
+		return
+	}
+	defer p.exit()
+
 	key := evt.ObservedKey()
-	shards := strings.Split(strings.TrimPrefix(key, "/"), "/")
-	if len(shards) == 2 {
-		observableID := shards[0]
-		category := shards[1]
-		grouphandlerAdress := observableID + "/" + category + "/" + "existifallpropertieswatched"
-		gmhs, ok := m.list[grouphandlerAdress]
-		if ok {
+	mhs, ok := m.list[key]
+	if ok {
+		mhs.Handle(evt)
+	}
+
+	origin := evt.Origin()
+	if origin != nil {
+		grouphandlerAdress := origin.ID + "/" + evt.Type() + "/" + "existifallpropertieswatched"
+		if gmhs, ok := m.list[grouphandlerAdress]; ok && grouphandlerAdress != key {
 			gmhs.Handle(evt)
 		}
 	}
 
-	mhs, ok := m.list[evt.ObservedKey()]
-	if !ok {
+	m.dispatchPatterns(evt)
+
+	if origin == nil || origin.PropMutationHandlers != m {
+		return
+	}
+	for p := origin.Parent; p != nil; p = p.Parent {
+		p.PropMutationHandlers.dispatchPatterns(evt)
+	}
+	if origin.ElementStore != nil && origin.ElementStore.PatternMutationHandlers != nil {
+		origin.ElementStore.PatternMutationHandlers.dispatchPatterns(evt)
+	}
+}
+
+func (m *MutationCallbacks) dispatchPatterns(evt MutationEvent) {
+	if len(m.patterns) == 0 {
 		return
 	}
-	mhs.Handle(evt)
+	key := evt.ObservedKey()
+	for pattern, mhs := range m.patterns {
+		var match bool
+		if origin := evt.Origin(); origin != nil {
+			match = matchObservedKey(pattern, origin.ID, key)
+		} else {
+			match = MatchKey(pattern, key)
+		}
+		if match {
+			mhs.Handle(evt)
+		}
+	}
+}
+
+// MatchKey reports whether an observed key, i.e. "elementID/category/propname",
+// matches a pattern.
+// Patterns are made of "/" separated segments. A segment is matched against the
+// corresponding key segment with the syntax of path.Match, so that "*" matches
+// any segment and "list-*" any segment starting with "list-". The "**" segment
+// matches any number of key segments.
+// For instance, "*/css/*" matches every css property mutation while "id/**"
+// matches every mutation of the properties of the Element with ID "id".
+// Since the key is split on "/", an Element ID containing "/" is only matched
+// as a single segment by the dispatch of MutationEvents, which knows it.
+func MatchKey(pattern string, key string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(key, "/"))
+}
+
+// matchObservedKey reports whether the observed key of a property of the
+// Element with the given ID matches a pattern. The ID is matched as a single
+// segment, even if it contains "/", in which case the leading segments of the
+// pattern make up the ID segment. Patterns holding "**" are matched against the
+// "/" separated segments of the key.
+func matchObservedKey(pattern string, id string, key string) bool {
+	category, propname, ok := splitObservedKey(id, key)
+	if !ok || !strings.Contains(id, "/") || strings.Contains(pattern, "**") {
+		return MatchKey(pattern, key)
+	}
+	const sep = "\x00" // stands for "/" within the ID segment, which path.Match treats as a separator
+	segments := strings.Split(pattern, "/")
+	if n := len(segments); n > 3 {
+		segments = append([]string{strings.Join(segments[:n-2], sep)}, segments[n-2:]...)
+	}
+	return matchSegments(segments, []string{strings.ReplaceAll(id, "/", sep), category, propname})
+}
+
+// splitObservedKey returns the category and the property name of an observed
+// key of the form id/category/propname.
+func splitObservedKey(id string, key string) (string, string, bool) {
+	if !strings.HasPrefix(key, id+"/") {
+		return "", "", false
+	}
+	rest := strings.TrimPrefix(key, id+"/")
+	i := strings.Index(rest, "/")
+	if i < 0 {
+		return "", "", false
+	}
+	return rest[:i], rest[i+1:], true
+}
+
+func matchSegments(pattern []string, key []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(key); i++ {
+				if matchSegments(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(key) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], key[0]); err != nil || !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		key = key[1:]
+	}
+	return len(key) == 0
+}
+
+// validatePattern returns an error if a segment of the pattern is malformed.
+func validatePattern(pattern string) error {
+	for _, seg := range strings.Split(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("%w %q: %v", ErrInvalidPattern, pattern, err)
+		}
+	}
+	return nil
 }
 
 type mutationHandlers struct {
@@ -62,11 +402,80 @@ func newMutationHandlers() *mutationHandlers {
 	return &mutationHandlers{make([]*MutationHandler, 0)}
 }
 
+// Add inserts a handler in the list, after the handlers of the same or of an
+// earlier phase, unless it has to be positioned relative to a named handler.
+// If h is the first handler of its name, the handlers which were waiting for it
+// to be registered are moved next to it.
 func (m *mutationHandlers) Add(h *MutationHandler) *mutationHandlers {
-	m.list = append(m.list, h)
+	m.insert(h)
+	if h.Name == "" || m.named(h.Name, h) != nil {
+		return m
+	}
+	var waiting []*MutationHandler
+	for _, v := range m.list {
+		if v != h && (v.before == h.Name || v.after == h.Name) {
+			waiting = append(waiting, v)
+		}
+	}
+	for _, v := range waiting {
+		m.Remove(v)
+		m.insert(v)
+	}
 	return m
 }
 
+func (m *mutationHandlers) insert(h *MutationHandler) {
+	index := len(m.list)
+	if i, ok := m.anchor(h); ok {
+		index = i
+	} else {
+		for k, v := range m.list {
+			if v.Phase > h.Phase {
+				index = k
+				break
+			}
+		}
+	}
+	if index == len(m.list) {
+		m.list = append(m.list, h)
+		return
+	}
+	// a new slice is allocated so that an ongoing dispatch is not disrupted
+	l := make([]*MutationHandler, 0, len(m.list)+1)
+	l = append(l, m.list[:index]...)
+	l = append(l, h)
+	m.list = append(l, m.list[index:]...)
+}
+
+// anchor returns the insertion index of a handler which has to be positioned
+// before or after a named handler, if the latter is present.
+func (m *mutationHandlers) anchor(h *MutationHandler) (int, bool) {
+	name, offset := h.before, 0
+	if name == "" {
+		name, offset = h.after, 1
+	}
+	if name == "" {
+		return -1, false
+	}
+	for k, v := range m.list {
+		if v.Name == name {
+			h.Phase = v.Phase // keeps the list ordered by phase
+			return k + offset, true
+		}
+	}
+	return -1, false
+}
+
+// named returns the handler of the given name other than h, if any.
+func (m *mutationHandlers) named(name string, h *MutationHandler) *MutationHandler {
+	for _, v := range m.list {
+		if v != h && v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
 func (m *mutationHandlers) Remove(h *MutationHandler) *mutationHandlers {
 	index := -1
 	for k, v := range m.list {
@@ -77,7 +486,10 @@ func (m *mutationHandlers) Remove(h *MutationHandler) *mutationHandlers {
 		break
 	}
 	if index >= 0 {
-		m.list = append(m.list[:index], m.list[index+1:]...)
+		// a new slice is allocated so that an ongoing dispatch is not disrupted
+		l := make([]*MutationHandler, 0, len(m.list)-1)
+		l = append(l, m.list[:index]...)
+		m.list = append(l, m.list[index+1:]...)
 	}
 	return m
 }
@@ -91,14 +503,60 @@ func (m *mutationHandlers) Handle(evt MutationEvent) {
 	}
 }
 
+// HandlerPhase determines when a MutationHandler runs relative to the other
+// handlers registered for the same mutation.
+type HandlerPhase int
+
+const (
+	PrePhase     HandlerPhase = -1 // runs before the default handlers, e.g. to validate or intercept
+	DefaultPhase HandlerPhase = 0
+	PostPhase    HandlerPhase = 1 // runs after the default handlers
+)
+
 // MutationHandler is a wrapper type around a callback function run after a mutation
 // event occured.
+// Handlers run by phase, then in registration order. A handler returning true
+// stops the dispatch.
 type MutationHandler struct {
-	Fn func(MutationEvent) bool
+	Fn    func(MutationEvent) bool
+	Phase HandlerPhase
+	Name  string // optional, allows other handlers to be inserted before or after it
+
+	before string
+	after  string
 }
 
 func NewMutationHandler(f func(evt MutationEvent) bool) *MutationHandler {
-	return &MutationHandler{f}
+	return &MutationHandler{f, DefaultPhase, "", "", ""}
+}
+
+// InPhase sets the phase during which the handler runs.
+func (m *MutationHandler) InPhase(p HandlerPhase) *MutationHandler {
+	m.Phase = p
+	return m
+}
+
+// Named sets the name of the handler.
+func (m *MutationHandler) Named(name string) *MutationHandler {
+	m.Name = name
+	return m
+}
+
+// Before makes the handler run right before the named handler when registered
+// for the same mutation. The handler then runs in the phase of the named one.
+// Until such a handler is registered, the handler runs in its own phase.
+func (m *MutationHandler) Before(name string) *MutationHandler {
+	m.before = name
+	m.after = ""
+	return m
+}
+
+// After makes the handler run right after the named handler when registered
+// for the same mutation. See Before.
+func (m *MutationHandler) After(name string) *MutationHandler {
+	m.after = name
+	m.before = ""
+	return m
 }
 
 func (m *MutationHandler) Handle(evt MutationEvent) bool {
@@ -111,6 +569,12 @@ type MutationEvent interface {
 	Type() string
 	Origin() *Element
 	NewValue() Value
+	OldValue() Value // nil if the property did not exist before the mutation
+	Existed() bool   // whether the property existed before the mutation
+
+	ID() string             // unique identifier of the mutation
+	Source() MutationSource // where the mutation originates from
+	ParentID() string       // ID of the MutationEvent or UI Event whose handling caused the mutation, if any
 }
 
 // Mutation defines a basic implementation for Mutation Events.
@@ -119,13 +583,45 @@ type Mutation struct {
 	typ     string
 	Value   Value
 	Src     *Element
+
+	old     Value
+	existed bool
+
+	id     string
+	source MutationSource
+	parent string
 }
 
-func (m Mutation) ObservedKey() string { return m.KeyName }
-func (m Mutation) Origin() *Element    { return m.Src }
-func (m Mutation) Type() string        { return m.typ }
-func (m Mutation) NewValue() Value     { return m.Value }
+func (m Mutation) ObservedKey() string    { return m.KeyName }
+func (m Mutation) Origin() *Element       { return m.Src }
+func (m Mutation) Type() string           { return m.typ }
+func (m Mutation) NewValue() Value        { return m.Value }
+func (m Mutation) OldValue() Value        { return m.old }
+func (m Mutation) Existed() bool          { return m.existed }
+func (m Mutation) ID() string             { return m.id }
+func (m Mutation) Source() MutationSource { return m.source }
+func (m Mutation) ParentID() string       { return m.parent }
+
+// WithOldValue returns a copy of the Mutation holding the value of the property
+// prior to the mutation. existed is false if the property was not set.
+func (m Mutation) WithOldValue(old Value, existed bool) Mutation {
+	m.old = old
+	m.existed = existed
+	return m
+}
+
+// coalesce returns the event to dispatch in place of two successive mutation
+// events of the same property: the later one, holding the old value of the
+// earlier one.
+func coalesce(earlier MutationEvent, later MutationEvent) MutationEvent {
+	m, ok := later.(Mutation)
+	if !ok {
+		return later
+	}
+	return m.WithOldValue(earlier.OldValue(), earlier.Existed())
+}
 
 func (e *Element) NewMutationEvent(category string, propname string, newvalue Value) Mutation {
-	return Mutation{e.ID + "/" + category + "/" + propname, category, newvalue, e}
+	source, parent := e.propagation().origin()
+	return Mutation{e.ID + "/" + category + "/" + propname, category, newvalue, e, nil, false, newEventID("m"), source, parent}
 }
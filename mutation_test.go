@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// mirror makes a and b copy each other's ("data","x") property, which yields a
+// mutation cycle.
+func mirror(a, b *Element) {
+	a.Watch("data", "x", b, NewMutationHandler(func(evt MutationEvent) bool {
+		a.SetData("x", evt.NewValue())
+		return false
+	}))
+	b.Watch("data", "x", a, NewMutationHandler(func(evt MutationEvent) bool {
+		b.SetData("x", evt.NewValue())
+		return false
+	}))
+}
+
+func TestMutationCycle(t *testing.T) {
+	a := NewElement("a", "la", "test")
+	b := NewElement("b", "lb", "test")
+	mirror(a, b)
+	if err := a.SetData("x", Number(1)); !errors.Is(err, ErrMutationCycle) {
+		t.Fatalf("got %v, want ErrMutationCycle", err)
+	}
+	if err := a.SetData("y", Number(1)); err != nil {
+		t.Errorf("error reported by a later propagation: %v", err)
+	}
+
+	// A handler re-entering with a different value is not a cycle.
+	d := NewElement("d", "ld", "test")
+	d.Watch("data", "v", d, NewMutationHandler(func(evt MutationEvent) bool {
+		if evt.NewValue().(Number) > 10 {
+			d.SetData("v", Number(10))
+		}
+		return false
+	}))
+	if err := d.SetData("v", Number(15)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMaxPropagationDepth(t *testing.T) {
+	c := NewElement("c", "lc", "test")
+	c.Watch("data", "n", c, NewMutationHandler(func(evt MutationEvent) bool {
+		c.SetData("n", evt.NewValue().(Number)+1)
+		return false
+	}))
+	defer func(depth int) { MaxPropagationDepth = depth }(MaxPropagationDepth)
+	MaxPropagationDepth = 10
+	if err := c.SetData("n", Number(0)); !errors.Is(err, ErrMaxPropagationDepth) {
+		t.Errorf("got %v, want ErrMaxPropagationDepth", err)
+	}
+}
+
+func TestPropagationErrorScope(t *testing.T) {
+	a := NewElement("a", "sa", "test")
+	b := NewElement("b", "sb", "test")
+	z := NewElement("z", "sz", "test")
+	mirror(a, b)
+
+	var sibling error = errors.New("not called")
+	a.Watch("data", "x", a, NewMutationHandler(func(evt MutationEvent) bool {
+		sibling = z.SetData("unrelated", evt.NewValue())
+		return false
+	}).InPhase(PostPhase))
+
+	if err := a.SetData("x", Number(1)); !errors.Is(err, ErrMutationCycle) {
+		t.Errorf("origin: got %v, want ErrMutationCycle", err)
+	}
+	if sibling != nil {
+		t.Errorf("sibling Set: got %v, want nil", sibling)
+	}
+
+	sibling = errors.New("not called")
+	err := z.Batch(func(tx *Tx) error {
+		tx.Set(a, "data", "x", Number(2))
+		tx.Set(z, "data", "other", Number(2))
+		return nil
+	})
+	if !errors.Is(err, ErrMutationCycle) || sibling != nil {
+		t.Errorf("Tx: got %v and %v", err, sibling)
+	}
+
+	// With a Scheduler, the handlers' mutations are queued: the cycle is only
+	// interrupted by the limit on the number of flush rounds.
+	s := NewElementStore("scopestore", "test").SetScheduler(NewScheduler(nil))
+	a.ElementStore, b.ElementStore, z.ElementStore = s, s, s
+	sibling = errors.New("not called")
+	a.SetData("x", Number(3))
+	if err := s.Flush(); !errors.Is(err, ErrMaxPropagationDepth) || sibling != nil {
+		t.Errorf("Scheduler: got %v and %v", err, sibling)
+	}
+}
+
+// The mutations of distinct ElementStores are tracked separately, so that the
+// stores can be used from distinct goroutines.
+func TestConcurrentStores(t *testing.T) {
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		s, thing := newTestStore("concurrent" + strconv.Itoa(i))
+		a := thing("a", s.Global.ID+"-a")
+		b := thing("b", s.Global.ID+"-b")
+		mirror(a, b)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				if err := a.SetData("x", Number(n)); !errors.Is(err, ErrMutationCycle) {
+					errs <- err
+					return
+				}
+				if err := s.Global.SetData("n", Number(n)); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("got %v", err)
+	}
+}
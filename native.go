@@ -34,5 +34,6 @@ func (n NativeEventUnlisteners) Apply(event string) {
 	if !ok {
 		return
 	}
+	delete(n.List, event)
 	removeNativeEventListener()
 }
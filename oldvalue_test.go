@@ -0,0 +1,37 @@
+package ui
+
+import "testing"
+
+func TestOldValue(t *testing.T) {
+	_, thing := newTestStore("oldvaluestore")
+	e := thing("e", "oldvalue")
+	var last MutationEvent
+	e.Watch("data", "x", e, NewMutationHandler(func(evt MutationEvent) bool {
+		last = evt
+		return false
+	}))
+
+	tests := []struct {
+		name    string
+		mutate  func() error
+		old     Value
+		existed bool
+	}{
+		{"first set", func() error { return e.SetData("x", Number(1)) }, nil, false},
+		{"overwrite", func() error { return e.SetData("x", Number(2)) }, Number(1), true},
+		{"delete", func() error { return e.Delete("data", "x") }, Number(2), true},
+		{"set after delete", func() error { return e.SetData("x", Number(3)) }, nil, false},
+	}
+	for _, tt := range tests {
+		last = nil
+		if err := tt.mutate(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if last == nil {
+			t.Fatalf("%s: no MutationEvent dispatched", tt.name)
+		}
+		if last.Existed() != tt.existed || !Equal(last.OldValue(), tt.old) {
+			t.Errorf("%s: got old value %v (existed: %v), want %v (existed: %v)", tt.name, last.OldValue(), last.Existed(), tt.old, tt.existed)
+		}
+	}
+}
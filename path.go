@@ -0,0 +1,213 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPath = errors.New("invalid property path")
+)
+
+// Property paths are dot-separated lists of keys. The first key is the name of
+// the property. The following ones are Object keys or List indices leading to
+// a nested Value. For instance, "user.address.city" designates the "city"
+// entry of the "address" Object stored in the "user" property.
+
+func splitPath(path string) (string, []string) {
+	keys := strings.Split(path, ".")
+	return keys[0], keys[1:]
+}
+
+// GetPath retrieves the Value located at the given path within the properties
+// of a category.
+func (e *Element) GetPath(category string, path string) (Value, bool) {
+	propname, keys := splitPath(path)
+	v, ok := e.Get(category, propname)
+	if !ok {
+		return nil, false
+	}
+	return valueAt(v, keys)
+}
+
+// SetPath replaces the Value located at the given path within the properties
+// of a category. The property holding the nested Value is updated via Set
+// with a modified copy so that previously retrieved Values are left untouched.
+// Missing Object entries along the path are created. List indices have to be
+// within range.
+func (e *Element) SetPath(category string, path string, value Value, flags ...bool) error {
+	propname, keys := splitPath(path)
+	if len(keys) == 0 {
+		return e.Set(category, propname, value, flags...)
+	}
+	current, _ := e.Get(category, propname)
+	v, err := setAt(current, keys, value)
+	if err != nil {
+		return fmt.Errorf("%w: %s/%s: %v", ErrInvalidPath, category, path, err)
+	}
+	return e.Set(category, propname, v, flags...)
+}
+
+// WatchPath registers a MutationHandler which is only called when the Value
+// located at the given path of a property of the owner changes.
+// The MutationEvent the handler receives has the full path as observed key,
+// i.e. owner.ID + "/" + category + "/" + path, and the nested Value as new value.
+func (e *Element) WatchPath(category string, path string, owner *Element, h *MutationHandler) *Subscription {
+	propname, keys := splitPath(path)
+	if len(keys) == 0 {
+		return e.Watch(category, propname, owner, h)
+	}
+	current, existed := owner.GetPath(category, path)
+	last := copyValue(current)
+
+	ph := NewMutationHandler(func(evt MutationEvent) bool {
+		v, ok := valueAt(evt.NewValue(), keys)
+		if Equal(last, v) {
+			return false
+		}
+		old, oldexisted := last, existed
+		last, existed = copyValue(v), ok
+		return h.Handle(Mutation{owner.ID + "/" + category + "/" + path, category, v, owner, old, oldexisted, evt.ID(), evt.Source(), evt.ParentID()})
+	})
+	ph.Phase, ph.Name, ph.before, ph.after = h.Phase, h.Name, h.before, h.after
+	return e.Watch(category, propname, owner, ph)
+}
+
+func valueAt(v Value, keys []string) (Value, bool) {
+	for _, k := range keys {
+		switch t := v.(type) {
+		case Object:
+			v, _ = t[k].(Value)
+		case Command:
+			v, _ = t[k].(Value)
+		case MutationRecord:
+			v, _ = t[k].(Value)
+		case ImmutableObject:
+			v, _ = t.Get(k)
+		case List:
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, false
+			}
+			v = t[i]
+		case ImmutableList:
+			i, err := strconv.Atoi(k)
+			if err != nil {
+				return nil, false
+			}
+			v = t.At(i)
+		default:
+			return nil, false
+		}
+		if v == nil {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// setAt returns a copy of v in which the Value located at keys is replaced.
+func setAt(v Value, keys []string, nv Value) (Value, error) {
+	if len(keys) == 0 {
+		return nv, nil
+	}
+	k := keys[0]
+	switch t := v.(type) {
+	case nil:
+		return setAt(NewObject(), keys, nv)
+	case Object:
+		return setEntry(t, keys, nv)
+	case Command:
+		o, err := setEntry(Object(t), keys, nv)
+		return Command(o), err
+	case MutationRecord:
+		o, err := setEntry(Object(t), keys, nv)
+		return MutationRecord(o), err
+	case ImmutableObject:
+		child, _ := t.Get(k)
+		c, err := setAt(child, keys[1:], nv)
+		if err != nil {
+			return nil, err
+		}
+		return t.With(k, c), nil
+	case List:
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= len(t) {
+			return nil, fmt.Errorf("index %q out of range", k)
+		}
+		c, err := setAt(t[i], keys[1:], nv)
+		if err != nil {
+			return nil, err
+		}
+		l := make(List, len(t))
+		copy(l, t)
+		l[i] = c
+		return l, nil
+	case ImmutableList:
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= t.Len() {
+			return nil, fmt.Errorf("index %q out of range", k)
+		}
+		c, err := setAt(t.At(i), keys[1:], nv)
+		if err != nil {
+			return nil, err
+		}
+		return t.SetAt(i, c), nil
+	default:
+		return nil, fmt.Errorf("cannot access %q within a %s", k, v.ValueType())
+	}
+}
+
+func setEntry(o Object, keys []string, nv Value) (Object, error) {
+	child, _ := o[keys[0]].(Value)
+	c, err := setAt(child, keys[1:], nv)
+	if err != nil {
+		return nil, err
+	}
+	p := Object(make(map[string]interface{}, len(o)+1))
+	for key, val := range o {
+		p[key] = val
+	}
+	p[keys[0]] = c
+	return p, nil
+}
+
+// copyValue returns a deep copy of the mutable container Values so that a
+// reference kept for later comparison cannot be modified in place.
+func copyValue(v Value) Value {
+	switch t := v.(type) {
+	case Object:
+		return copyObject(t)
+	case Command:
+		return Command(copyObject(Object(t)))
+	case MutationRecord:
+		return MutationRecord(copyObject(Object(t)))
+	case List:
+		l := make(List, len(t))
+		for i, item := range t {
+			l[i] = copyValue(item)
+		}
+		return l
+	case Bytes:
+		b := make(Bytes, len(t))
+		copy(b, t)
+		return b
+	default:
+		return v
+	}
+}
+
+func copyObject(o Object) Object {
+	p := Object(make(map[string]interface{}, len(o)))
+	for k, val := range o {
+		if v, ok := val.(Value); ok {
+			p[k] = copyValue(v)
+			continue
+		}
+		p[k] = val
+	}
+	return p
+}
@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetPath(t *testing.T) {
+	_, ctor := newTestStore("getpathstore")
+	e := ctor("e", "getpath-e")
+	address := NewObject()
+	address.Set("city", String("Paris"))
+	user := NewObject()
+	user.Set("address", address)
+	user.Set("tags", NewList(String("a"), String("b")))
+	e.SetData("user", user)
+
+	tests := []struct {
+		path string
+		want Value
+		ok   bool
+	}{
+		{"user.address.city", String("Paris"), true},
+		{"user.tags.1", String("b"), true},
+		{"user", user, true},
+		{"user.phone.number", nil, false},
+		{"user.tags.2", nil, false},
+		{"user.tags.x", nil, false},
+		{"user.address.city.x", nil, false},
+		{"missing.x", nil, false},
+	}
+	for _, test := range tests {
+		v, ok := e.GetPath("data", test.path)
+		if ok != test.ok || !Equal(v, test.want) {
+			t.Errorf("%s: got %v, %v, want %v, %v", test.path, v, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	_, ctor := newTestStore("setpathstore")
+	e := ctor("e", "setpath-e")
+
+	if err := e.SetPath("data", "user.address.city", String("Paris")); err != nil {
+		t.Fatalf("missing intermediate keys: %v", err)
+	}
+	before, _ := e.GetData("user")
+	if err := e.SetPath("data", "user.address.city", String("Lyon")); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := valueAt(before, []string{"address", "city"}); v != String("Paris") {
+		t.Errorf("previously retrieved Value modified: got %v", v)
+	}
+
+	e.SetData("l", NewList(NewObject()))
+	if err := e.SetPath("data", "l.0.x", Number(1)); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := e.GetPath("data", "l.0.x"); v != Number(1) {
+		t.Errorf("got %v, want 1", v)
+	}
+	for _, path := range []string{"l.1.x", "l.-1", "l.x", "user.address.city.x"} {
+		if err := e.SetPath("data", path, Number(1)); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("%s: got %v, want ErrInvalidPath", path, err)
+		}
+	}
+}
+
+func TestWatchPath(t *testing.T) {
+	_, ctor := newTestStore("watchpathstore")
+	e := ctor("e", "watchpath-e")
+	w := ctor("w", "watchpath-w")
+	var got []Value
+	w.WatchPath("data", "user.address.city", e, NewMutationHandler(func(evt MutationEvent) bool {
+		if evt.ObservedKey() != "watchpath-e/data/user.address.city" {
+			t.Errorf("got observed key %s", evt.ObservedKey())
+		}
+		got = append(got, evt.NewValue())
+		return false
+	}))
+
+	e.SetPath("data", "user.address.city", String("Paris"))
+	e.SetPath("data", "user.name", String("Bob"))
+	e.SetPath("data", "user.address.zip", String("75001"))
+	e.SetPath("data", "user.address.city", String("Paris"))
+	e.SetPath("data", "user.address.city", String("Lyon"))
+	if len(got) != 2 || got[0] != String("Paris") || got[1] != String("Lyon") {
+		t.Errorf("got %v, want [Paris Lyon]", got)
+	}
+}
@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatchKey(t *testing.T) {
+	tests := []struct {
+		pattern, key string
+		match        bool
+	}{
+		{"*/css/*", "a/css/color", true},
+		{"*/css/*", "a/data/x", false},
+		{"list-*/data/selected", "list-3/data/selected", true},
+		{"id/**", "id/css/color", true},
+		{"id/**", "di/css/color", false},
+		{"**/selected", "x/data/selected", true},
+	}
+	for _, test := range tests {
+		if got := MatchKey(test.pattern, test.key); got != test.match {
+			t.Errorf("MatchKey(%q, %q) = %v, want %v", test.pattern, test.key, got, test.match)
+		}
+	}
+}
+
+func TestMatchObservedKey(t *testing.T) {
+	tests := []struct {
+		pattern string
+		match   bool
+	}{
+		{"*/css/*", true},
+		{"*/css/color", true},
+		{"ab/cd/css/*", true},
+		{"ab*/css/*", true},
+		{"ab/*/css/*", true},
+		{"ab/**", true},
+		{"**/color", true},
+		{"*/*/css/color", true},
+		{"ab/css/*", false},
+		{"*/data/*", false},
+	}
+	for _, test := range tests {
+		if got := matchObservedKey(test.pattern, "ab/cd", "ab/cd/css/color"); got != test.match {
+			t.Errorf("%q: got %v, want %v", test.pattern, got, test.match)
+		}
+	}
+}
+
+func TestWatchPattern(t *testing.T) {
+	s, ctor := newTestStore("patternstore")
+	p := ctor("p", "pp")
+	c := ctor("c", "pc")
+	p.AppendChild(c)
+
+	var got []string
+	sub, err := p.WatchPattern("*/data/selected", NewMutationHandler(func(evt MutationEvent) bool {
+		got = append(got, evt.ObservedKey())
+		return false
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetData("selected", Bool(true))
+	c.SetData("other", Bool(true))
+	if len(got) != 1 || got[0] != "pc/data/selected" {
+		t.Errorf("got %v", got)
+	}
+	sub.Cancel()
+	c.SetData("selected", Bool(false))
+	if len(got) != 1 {
+		t.Errorf("handler called after Cancel: %v", got)
+	}
+
+	slashed := ctor("s", "ab/cd")
+	p.AppendChild(slashed)
+	got = nil
+	p.WatchPattern("*/css/*", recorder(&got, "css"))
+	slashed.Set("css", "color", String("red"))
+	if len(got) != 1 {
+		t.Errorf("Element ID holding a '/': got %d calls, want 1", len(got))
+	}
+
+	if _, err := p.WatchPattern("[", NewMutationHandler(func(MutationEvent) bool { return false })); !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("Element.WatchPattern: got %v, want ErrInvalidPattern", err)
+	}
+	if _, err := s.WatchPattern("a/[", p, NewMutationHandler(func(MutationEvent) bool { return false })); !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("ElementStore.WatchPattern: got %v, want ErrInvalidPattern", err)
+	}
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+// phaseRecorder returns a function creating handlers which record their label
+// in *got when called.
+func phaseRecorder(e *Element, got *[]string) func(label string, h func(*MutationHandler) *MutationHandler) {
+	return func(label string, h func(*MutationHandler) *MutationHandler) {
+		e.Watch("data", "x", e, h(recorder(got, label)))
+	}
+}
+
+func same(h *MutationHandler) *MutationHandler { return h }
+
+func TestHandlerPhases(t *testing.T) {
+	_, thing := newTestStore("phasestore")
+	e := thing("e", "phases")
+	var got []string
+	add := phaseRecorder(e, &got)
+
+	add("post", func(h *MutationHandler) *MutationHandler { return h.InPhase(PostPhase) })
+	add("default1", same)
+	add("pre", func(h *MutationHandler) *MutationHandler { return h.InPhase(PrePhase) })
+	add("default2", same)
+	add("pre2", func(h *MutationHandler) *MutationHandler { return h.InPhase(PrePhase) })
+	e.SetData("x", Number(1))
+
+	want := "pre pre2 default1 default2 post"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+}
+
+func TestHandlerAnchors(t *testing.T) {
+	_, thing := newTestStore("anchorstore")
+	e := thing("e", "anchors")
+	var got []string
+	add := phaseRecorder(e, &got)
+
+	add("first", same)
+	add("named", func(h *MutationHandler) *MutationHandler { return h.Named("named") })
+	add("last", same)
+	add("before", func(h *MutationHandler) *MutationHandler { return h.Before("named") })
+	add("after", func(h *MutationHandler) *MutationHandler { return h.After("named") })
+	// the phase of the named handler prevails
+	add("postbefore", func(h *MutationHandler) *MutationHandler { return h.InPhase(PostPhase).Before("named") })
+	e.SetData("x", Number(1))
+
+	want := "first before postbefore named after last"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+}
+
+func TestHandlerAnchorRegisteredLater(t *testing.T) {
+	_, thing := newTestStore("lateanchorstore")
+	e := thing("e", "lateanchors")
+	var got []string
+	add := phaseRecorder(e, &got)
+
+	add("before", func(h *MutationHandler) *MutationHandler { return h.InPhase(PostPhase).Before("late") })
+	add("after", func(h *MutationHandler) *MutationHandler { return h.InPhase(PrePhase).After("late") })
+	add("default", same)
+	e.SetData("x", Number(1))
+	if s, want := strings.Join(got, " "), "after default before"; s != want {
+		t.Errorf("before registration: got %q, want %q", s, want)
+	}
+
+	got = nil
+	add("first", same)
+	add("late", func(h *MutationHandler) *MutationHandler { return h.Named("late") })
+	add("last", same)
+	e.SetData("x", Number(2))
+	if s, want := strings.Join(got, " "), "default first before late after last"; s != want {
+		t.Errorf("after registration: got %q, want %q", s, want)
+	}
+}
+
+func TestHandlerBeforeDefaultCommandHandler(t *testing.T) {
+	_, thing := newTestStore("vetostore")
+	parent := thing("parent", "vetoparent")
+	child := thing("child", "vetochild")
+	parent.Watch("ui", "command", parent, NewMutationHandler(func(evt MutationEvent) bool {
+		return true
+	}).Before("DefaultCommandHandler"))
+
+	parent.Mutate(AppendChildCommand(child))
+	if len(parent.Children.List) != 0 {
+		t.Error("command executed despite the handler stopping the dispatch")
+	}
+}
@@ -0,0 +1,488 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrInvalidSelector = errors.New("invalid selector")
+)
+
+// Querying
+//
+// Elements can be looked up within the subtree of an Element or among all the
+// Elements of an ElementStore, by constructor name, by Name, by predicate or by
+// selector.
+//
+// A selector is a comma-separated list of complex selectors. A complex selector
+// is a sequence of compound selectors separated by combinators: whitespace
+// selects descendants, ">" selects children. A compound selector is made of an
+// optional constructor name or "*", followed by any number of:
+//   - ".class" which matches Elements whose ("css","class") property contains
+//     the class
+//   - "#id" which matches the Element of the given ID
+//   - "[name=value]" which matches Elements whose Name is value. The value may be
+//     quoted.
+//
+// For instance: "list > item.selected, button[name=submit]"
+
+// ElementIter iterates over a sequence of Elements, calling yield for each of
+// them until it returns false.
+type ElementIter func(yield func(*Element) bool)
+
+// Collect returns the Elements of the sequence.
+func (it ElementIter) Collect() *Elements {
+	res := NewElements()
+	it(func(e *Element) bool {
+		res.InsertLast(e)
+		return true
+	})
+	return res
+}
+
+// First returns the first Element of the sequence, or nil if it is empty.
+func (it ElementIter) First() *Element {
+	var res *Element
+	it(func(e *Element) bool {
+		res = e
+		return false
+	})
+	return res
+}
+
+// Filter returns the sequence of the Elements for which fn returns true.
+func (it ElementIter) Filter(fn func(*Element) bool) ElementIter {
+	return func(yield func(*Element) bool) {
+		it(func(e *Element) bool {
+			if !fn(e) {
+				return true
+			}
+			return yield(e)
+		})
+	}
+}
+
+// Walk calls fn for the Element and each of its descendants, depth-first, with
+// parents before their children. The walk stops as soon as fn returns false.
+// If inactiveviews is true, the Elements of the views of a ViewElement which
+// are not currently active are walked as well, after the active children.
+// Walk returns false if it has been stopped.
+func (e *Element) Walk(fn func(*Element) bool, inactiveviews bool) bool {
+	if !fn(e) {
+		return false
+	}
+	for _, child := range e.Children.List {
+		if !child.Walk(fn, inactiveviews) {
+			return false
+		}
+	}
+	if !inactiveviews {
+		return true
+	}
+	names := make([]string, 0, len(e.InactiveViews))
+	for name := range e.InactiveViews {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, child := range e.InactiveViews[name].Elements().List {
+			if !child.Walk(fn, inactiveviews) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Descendants returns the descendants of the Element in depth-first order.
+// Elements of inactive views are not included.
+func (e *Element) Descendants() ElementIter {
+	return func(yield func(*Element) bool) {
+		for _, child := range e.Children.List {
+			if !child.Walk(yield, false) {
+				return
+			}
+		}
+	}
+}
+
+// Ancestors returns the ancestors of the Element, starting from its parent.
+func (e *Element) Ancestors() ElementIter {
+	return func(yield func(*Element) bool) {
+		for a := e.Parent; a != nil; a = a.Parent {
+			if !yield(a) {
+				return
+			}
+		}
+	}
+}
+
+// Siblings returns the other children of the parent of the Element, in order.
+func (e *Element) Siblings() ElementIter {
+	return func(yield func(*Element) bool) {
+		if e.Parent == nil {
+			return
+		}
+		for _, sibling := range e.Parent.Children.List {
+			if sibling == e {
+				continue
+			}
+			if !yield(sibling) {
+				return
+			}
+		}
+	}
+}
+
+// Find returns the descendants of the Element for which fn returns true.
+func (e *Element) Find(fn func(*Element) bool) *Elements {
+	return e.Descendants().Filter(fn).Collect()
+}
+
+// FindByConstructor returns the descendants of the Element which were created
+// by the named constructor.
+func (e *Element) FindByConstructor(constructorname string) *Elements {
+	return e.Find(constructedBy(constructorname))
+}
+
+// FindByName returns the descendants of the Element with the given Name.
+func (e *Element) FindByName(name string) *Elements {
+	return e.Find(named(name))
+}
+
+// FindByProperty returns the descendants of the Element holding a value for
+// the named property for which fn returns true.
+func (e *Element) FindByProperty(category string, propname string, fn func(Value) bool) *Elements {
+	return e.Find(hasProperty(category, propname, fn))
+}
+
+// QueryAll returns the descendants of the Element matching the selector.
+func (e *Element) QueryAll(selector string) (*Elements, error) {
+	s, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return e.Descendants().Filter(s.match).Collect(), nil
+}
+
+// Query returns the first descendant of the Element matching the selector, or
+// nil if there is none.
+func (e *Element) Query(selector string) (*Element, error) {
+	s, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return e.Descendants().Filter(s.match).First(), nil
+}
+
+// All returns the Elements of the ElementStore, ordered by ID.
+func (e *ElementStore) All() ElementIter {
+	return func(yield func(*Element) bool) {
+		ids := make([]string, 0, len(e.ByID))
+		for id := range e.ByID {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			if el, ok := e.ByID[id]; ok && !yield(el) {
+				return
+			}
+		}
+	}
+}
+
+// Find returns the Elements of the ElementStore for which fn returns true.
+func (e *ElementStore) Find(fn func(*Element) bool) *Elements {
+	return e.All().Filter(fn).Collect()
+}
+
+// FindByConstructor returns the Elements of the ElementStore which were
+// created by the named constructor.
+func (e *ElementStore) FindByConstructor(constructorname string) *Elements {
+	return e.Find(constructedBy(constructorname))
+}
+
+// FindByName returns the Elements of the ElementStore with the given Name.
+func (e *ElementStore) FindByName(name string) *Elements {
+	return e.Find(named(name))
+}
+
+// FindByProperty returns the Elements of the ElementStore holding a value for
+// the named property for which fn returns true.
+func (e *ElementStore) FindByProperty(category string, propname string, fn func(Value) bool) *Elements {
+	return e.Find(hasProperty(category, propname, fn))
+}
+
+// QueryAll returns the Elements of the ElementStore matching the selector.
+func (e *ElementStore) QueryAll(selector string) (*Elements, error) {
+	s, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return e.All().Filter(s.match).Collect(), nil
+}
+
+// Query returns the first Element of the ElementStore, by ID order, matching
+// the selector, or nil if there is none.
+func (e *ElementStore) Query(selector string) (*Element, error) {
+	s, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return e.All().Filter(s.match).First(), nil
+}
+
+func constructedBy(constructorname string) func(*Element) bool {
+	return func(e *Element) bool {
+		v, ok := e.Get("internals", "constructor")
+		return ok && v == String(constructorname)
+	}
+}
+
+func named(name string) func(*Element) bool {
+	return func(e *Element) bool {
+		return e.Name == name
+	}
+}
+
+func hasProperty(category string, propname string, fn func(Value) bool) func(*Element) bool {
+	return func(e *Element) bool {
+		v, ok := e.Get(category, propname)
+		return ok && fn(v)
+	}
+}
+
+func hasClass(e *Element, class string) bool {
+	v, ok := e.Get("css", "class")
+	if !ok {
+		return false
+	}
+	s, ok := v.(String)
+	if !ok {
+		return false
+	}
+	for _, c := range strings.Fields(string(s)) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// selector is a parsed selector, i.e. a list of alternative complex selectors.
+type selector []complexSelector
+
+func (s selector) match(e *Element) bool {
+	for _, c := range s {
+		if c.matchFrom(e, len(c.parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// complexSelector is a list of compound selectors. combinators[i] is the
+// combinator between parts[i] and parts[i+1], either ' ' or '>'.
+type complexSelector struct {
+	parts       []compoundSelector
+	combinators []byte
+}
+
+// matchFrom returns whether e matches parts[i] and its ancestors match the
+// preceding parts.
+func (c complexSelector) matchFrom(e *Element, i int) bool {
+	if !c.parts[i].match(e) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	if c.combinators[i-1] == '>' {
+		return e.Parent != nil && c.matchFrom(e.Parent, i-1)
+	}
+	for a := e.Parent; a != nil; a = a.Parent {
+		if c.matchFrom(a, i-1) {
+			return true
+		}
+	}
+	return false
+}
+
+type compoundSelector struct {
+	constructor string
+	id          string
+	name        *string
+	classes     []string
+}
+
+func (c compoundSelector) match(e *Element) bool {
+	if c.constructor != "" && !constructedBy(c.constructor)(e) {
+		return false
+	}
+	if c.id != "" && e.ID != c.id {
+		return false
+	}
+	if c.name != nil && e.Name != *c.name {
+		return false
+	}
+	for _, class := range c.classes {
+		if !hasClass(e, class) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectorParser parses selector strings.
+type selectorParser struct {
+	s   string
+	pos int
+}
+
+func parseSelector(s string) (selector, error) {
+	p := &selectorParser{s, 0}
+	var res selector
+	for {
+		c, err := p.complex()
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidSelector, s, err)
+		}
+		res = append(res, c)
+		if p.pos >= len(p.s) {
+			return res, nil
+		}
+		p.pos++ // ','
+	}
+}
+
+func (p *selectorParser) skipSpaces() bool {
+	start := p.pos
+	for p.pos < len(p.s) && isSelectorSpace(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+// complex parses a complex selector up to the next ',' or the end.
+func (p *selectorParser) complex() (complexSelector, error) {
+	var c complexSelector
+	p.skipSpaces()
+	for {
+		cs, err := p.compound()
+		if err != nil {
+			return c, err
+		}
+		c.parts = append(c.parts, cs)
+
+		spaces := p.skipSpaces()
+		if p.pos >= len(p.s) || p.s[p.pos] == ',' {
+			return c, nil
+		}
+		if p.s[p.pos] == '>' {
+			p.pos++
+			p.skipSpaces()
+			c.combinators = append(c.combinators, '>')
+			continue
+		}
+		if !spaces {
+			return c, fmt.Errorf("unexpected %q at offset %d", p.s[p.pos], p.pos)
+		}
+		c.combinators = append(c.combinators, ' ')
+	}
+}
+
+func (p *selectorParser) compound() (compoundSelector, error) {
+	var c compoundSelector
+	start := p.pos
+	if p.pos < len(p.s) && p.s[p.pos] == '*' {
+		p.pos++
+	} else {
+		c.constructor = p.ident()
+	}
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '.':
+			p.pos++
+			class := p.ident()
+			if class == "" {
+				return c, fmt.Errorf("missing class name at offset %d", p.pos)
+			}
+			c.classes = append(c.classes, class)
+		case '#':
+			p.pos++
+			c.id = p.ident()
+			if c.id == "" {
+				return c, fmt.Errorf("missing id at offset %d", p.pos)
+			}
+		case '[':
+			p.pos++
+			name, err := p.attribute()
+			if err != nil {
+				return c, err
+			}
+			c.name = &name
+		default:
+			if p.pos == start {
+				return c, fmt.Errorf("unexpected %q at offset %d", p.s[p.pos], p.pos)
+			}
+			return c, nil
+		}
+	}
+	if p.pos == start {
+		return c, errors.New("empty selector")
+	}
+	return c, nil
+}
+
+// attribute parses the remainder of a "[name=value]" attribute selector and
+// returns the value.
+func (p *selectorParser) attribute() (string, error) {
+	p.skipSpaces()
+	if attr := p.ident(); attr != "name" {
+		return "", fmt.Errorf("unsupported attribute %q, only name is supported", attr)
+	}
+	p.skipSpaces()
+	if p.pos >= len(p.s) || p.s[p.pos] != '=' {
+		return "", fmt.Errorf("missing '=' at offset %d", p.pos)
+	}
+	p.pos++
+	p.skipSpaces()
+	var value string
+	if p.pos < len(p.s) && (p.s[p.pos] == '"' || p.s[p.pos] == '\'') {
+		quote := p.s[p.pos]
+		end := strings.IndexByte(p.s[p.pos+1:], quote)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string at offset %d", p.pos)
+		}
+		value = p.s[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+	} else {
+		value = p.ident()
+	}
+	p.skipSpaces()
+	if p.pos >= len(p.s) || p.s[p.pos] != ']' {
+		return "", fmt.Errorf("missing ']' at offset %d", p.pos)
+	}
+	p.pos++
+	return value, nil
+}
+
+func (p *selectorParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.s) && isSelectorIdentChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func isSelectorSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isSelectorIdentChar(c byte) bool {
+	return c == '-' || c == '_' || c == ':' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// queryTree returns an app root holding the following tree, where Elements are
+// denoted by constructor#id.name.classes:
+//
+//	thing#ql.main
+//	├── item#qa.first
+//	├── item#qb.second.sel.big
+//	└── thing#qn.nested
+//	    └── item#qc.third.sel
+func queryTree(storeid string) (*ElementStore, *Element) {
+	s, thing := newTestStore(storeid)
+	item := s.NewConstructor("item", func(name, id string) *Element { return NewElement(name, id, "test") })
+	root := s.NewAppRoot("qroot")
+	l := thing("main", "ql")
+	a := item("first", "qa")
+	b := item("second", "qb")
+	n := thing("nested", "qn")
+	c := item("third", "qc")
+	b.Set("css", "class", String("sel big"))
+	c.Set("css", "class", String("sel"))
+	root.AppendChild(l)
+	l.AppendChild(a).AppendChild(b).AppendChild(n)
+	n.AppendChild(c)
+	return s, root
+}
+
+func ids(es *Elements) string {
+	l := make([]string, 0, len(es.List))
+	for _, e := range es.List {
+		l = append(l, e.ID)
+	}
+	return strings.Join(l, " ")
+}
+
+func TestQueryAll(t *testing.T) {
+	_, root := queryTree("querystore")
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"item", "qa qb qc"},
+		{"*", "ql qa qb qn qc"},
+		{"#qn", "qn"},
+		{"thing > item", "qa qb qc"},
+		{"#ql > item", "qa qb"},
+		{"thing thing item", "qc"},
+		{".sel", "qb qc"},
+		{"item.sel.big", "qb"},
+		{"[name=third]", "qc"},
+		{"*[name='third'], #qa", "qa qc"},
+		{"  thing  >  .sel ", "qb qc"},
+		{"item > item", ""},
+		{"#missing", ""},
+		{".sel.missing", ""},
+	}
+	for _, tt := range tests {
+		es, err := root.QueryAll(tt.selector)
+		if err != nil {
+			t.Errorf("%q: %v", tt.selector, err)
+			continue
+		}
+		if got := ids(es); got != tt.want {
+			t.Errorf("%q: got [%s], want [%s]", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestQueryInvalidSelector(t *testing.T) {
+	s, root := queryTree("queryinvalid")
+	for _, selector := range []string{"", " ", "item,", ", item", "item >", "> item", "item..sel", "item.", "#", "item$", "[id=qa]", "[name=qa", "[name qa]", "[name='qa]"} {
+		if _, err := root.QueryAll(selector); !errors.Is(err, ErrInvalidSelector) {
+			t.Errorf("%q: got %v, want ErrInvalidSelector", selector, err)
+		}
+		if _, err := s.Query(selector); !errors.Is(err, ErrInvalidSelector) {
+			t.Errorf("store %q: got %v, want ErrInvalidSelector", selector, err)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	s, root := queryTree("queryfirst")
+	if e, err := root.Query(".sel"); err != nil || e == nil || e.ID != "qb" {
+		t.Errorf("got %v, %v, want the first match in document order", e, err)
+	}
+	if e, err := root.Query("item.missing"); err != nil || e != nil {
+		t.Errorf("got %v, %v, want no match", e, err)
+	}
+	if got := ids(s.FindByConstructor("item")); got != "qa qb qc" {
+		t.Errorf("FindByConstructor: got [%s]", got)
+	}
+	if e, _ := s.Query("item.big"); e == nil || e.ID != "qb" {
+		t.Errorf("store Query: got %v", e)
+	}
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// money is an application-defined Value.
+type money struct {
+	CustomValue
+	Amount   float64
+	Currency string
+}
+
+func (m money) ValueType() string { return "Money" }
+
+func (m money) RawValue() Object {
+	o := NewObject().SetType("Money")
+	o.Set("amount", Number(m.Amount))
+	o.Set("currency", String(m.Currency))
+	return o.RawValue()
+}
+
+func decodeMoney(raw Object) (Value, error) {
+	amount, ok := raw.Get("amount")
+	if !ok {
+		return nil, errors.New("missing amount")
+	}
+	currency, _ := raw.Get("currency")
+	return money{Amount: float64(amount.(Number)), Currency: string(currency.(String))}, nil
+}
+
+func init() {
+	if err := RegisterValueType("Money", decodeMoney); err != nil {
+		panic(err)
+	}
+}
+
+// unregistered is an application-defined Value whose type is not registered.
+type unregistered struct {
+	CustomValue
+}
+
+func (u unregistered) ValueType() string { return "Unregistered" }
+func (u unregistered) RawValue() Object  { return NewObject().SetType("Unregistered").RawValue() }
+
+func TestRegisteredValueRoundTrip(t *testing.T) {
+	price := money{Amount: 3.5, Currency: "EUR"}
+	obj := NewObject()
+	obj.Set("price", price)
+	cmd := NewUICommand().Name("pay")
+	Object(cmd).Set("payload", NewList(price, obj))
+
+	tests := map[string]Value{
+		"Value":   price,
+		"List":    NewList(String("x"), price),
+		"Object":  obj,
+		"Command": cmd,
+	}
+	for name, v := range tests {
+		b, err := MarshalValue(v)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		r, err := UnmarshalValue(b)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(r, v) {
+			t.Errorf("%s: got %#v, want %#v", name, r, v)
+		}
+	}
+}
+
+func TestRegisterValueTypeErrors(t *testing.T) {
+	if err := RegisterValueType("Bool", decodeMoney); !errors.Is(err, ErrValueTypeExists) {
+		t.Errorf("built-in type: got %v, want ErrValueTypeExists", err)
+	}
+	if err := RegisterValueType("Money", decodeMoney); !errors.Is(err, ErrValueTypeExists) {
+		t.Errorf("registered type: got %v, want ErrValueTypeExists", err)
+	}
+
+	for _, v := range []Value{unregistered{}, NewList(unregistered{})} {
+		if _, err := MarshalValue(v); !errors.Is(err, ErrUnregisteredValueType) {
+			t.Errorf("%T: got %v, want ErrUnregisteredValueType", v, err)
+		}
+	}
+
+	payload := fmt.Sprintf(`{"version":%d,"value":{"typ":"Money","currency":{"typ":"String","value":"EUR"}}}`, ValueCodecVersion)
+	if _, err := UnmarshalValue([]byte(payload)); !errors.Is(err, ErrInvalidRawValue) {
+		t.Errorf("failed decoding: got %v, want ErrInvalidRawValue", err)
+	}
+}
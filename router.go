@@ -104,6 +104,7 @@ func (r *Router) tryNavigate(newroute string) bool {
 
 // GoTo changes the application state by updating the current route
 func (r *Router) GoTo(route string) {
+	defer r.outlet.Element().enterMutationSource(SourceRouter)()
 	route = strings.TrimPrefix(route, r.BaseURL)
 	route = strings.TrimPrefix(route, "/")
 	if !r.LeaveTrailingSlash {
@@ -122,18 +123,21 @@ func (r *Router) GoTo(route string) {
 }
 
 func (r *Router) GoBack() {
+	defer r.outlet.Element().enterMutationSource(SourceRouter)()
 	if r.History.BackAllowed() {
 		r.outlet.Element().Root().Set("navigation", "routechangerequest", String(r.History.Back()))
 	}
 }
 
 func (r *Router) GoForward() {
+	defer r.outlet.Element().enterMutationSource(SourceRouter)()
 	if r.History.ForwardAllowed() {
 		r.outlet.Element().Root().Set("navigation", "routechangerequest", String(r.History.Forward()))
 	}
 }
 
 func (r *Router) RedirectTo(route string) {
+	defer r.outlet.Element().enterMutationSource(SourceRouter)()
 	route = strings.TrimPrefix(route, r.BaseURL)
 	route = strings.TrimPrefix(route, "/")
 	if !r.LeaveTrailingSlash {
@@ -196,6 +200,7 @@ func (r *Router) Match(route string) error {
 // handler returns a mutation handler which deals with route change.
 func (r *Router) handler() *MutationHandler {
 	mh := NewMutationHandler(func(evt MutationEvent) bool {
+		defer r.outlet.Element().enterMutationSource(SourceRouter)()
 		nroute, ok := evt.NewValue().(String)
 		if !ok {
 			log.Print("route mutation has wrong type... something must be wrong", evt.NewValue())
@@ -245,6 +250,7 @@ func (r *Router) handler() *MutationHandler {
 // redirecthandler returns a mutation handler which deals with route redirections.
 func (r *Router) redirecthandler() *MutationHandler {
 	mh := NewMutationHandler(func(evt MutationEvent) bool {
+		defer r.outlet.Element().enterMutationSource(SourceRouter)()
 		nroute, ok := evt.NewValue().(String)
 		if !ok {
 			log.Print("route mutation has wrong type... something must be wrong", evt.NewValue())
@@ -329,6 +335,7 @@ func (r *Router) ListenAndServe(eventname string, target *Element, nativebinding
 	}
 
 	routeChangeHandler := NewEventHandler(func(evt Event) bool {
+		defer r.outlet.Element().enterMutationSource(SourceRouter)()
 		if evt.Type() != eventname {
 			log.Print("Event of wrong type. Expected: " + eventname)
 			root.Element().Root().Set("navigation", "appfailure", String("500: RouteChangeEvent of wrong type."))
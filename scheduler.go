@@ -0,0 +1,128 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Scheduler defers the dispatch of the MutationEvents of the Elements of an
+// ElementStore until a flush point.
+// Queued events are deduplicated per observed key: only the last value set for
+// a property is dispatched. Events are dispatched in the order in which their
+// key was first queued. Events queued by the handlers during a flush are
+// dispatched by the same flush, once the current ones have been.
+//
+// An ElementStore without Scheduler dispatches MutationEvents synchronously,
+// which is the default.
+type Scheduler struct {
+	// Tick, if not nil, is called with the flush function whenever an event is
+	// queued while no flush is pending. Typically, a driver will provide a
+	// function that calls flush on the next animation frame.
+	Tick func(flush func())
+
+	queue     []MutationEvent
+	index     map[string]int
+	scheduled bool
+	flushing  bool
+}
+
+// NewScheduler returns a Scheduler. If tick is nil, queued events are only
+// dispatched by explicit calls to Flush.
+func NewScheduler(tick func(flush func())) *Scheduler {
+	return &Scheduler{tick, nil, make(map[string]int), false, false}
+}
+
+func (s *Scheduler) schedule(evt MutationEvent) {
+	key := evt.ObservedKey()
+	if i, ok := s.index[key]; ok {
+		s.queue[i] = coalesce(s.queue[i], evt)
+		return
+	}
+	s.index[key] = len(s.queue)
+	s.queue = append(s.queue, evt)
+
+	if s.Tick != nil && !s.scheduled && !s.flushing {
+		s.scheduled = true
+		s.Tick(func() {
+			if err := s.Flush(); err != nil {
+				log.Print(err)
+			}
+		})
+	}
+}
+
+// Pending returns the number of queued MutationEvents.
+func (s *Scheduler) Pending() int {
+	return len(s.queue)
+}
+
+// Flush dispatches the queued MutationEvents.
+// Since handlers may keep on queueing events, the number of successive
+// dispatch rounds is limited by MaxPropagationDepth. Past this limit, the
+// remaining events are dropped and an error is returned.
+// Calling Flush from within a handler has no effect: the events it would
+// dispatch are dispatched by the ongoing flush.
+func (s *Scheduler) Flush() error {
+	if s.flushing {
+		return nil
+	}
+	s.flushing = true
+	s.scheduled = false
+	defer func() { s.flushing = false }()
+
+	var err error
+	for round := 0; len(s.queue) > 0; round++ {
+		batch := s.queue
+		s.queue = nil
+		s.index = make(map[string]int)
+
+		if round >= MaxPropagationDepth {
+			keys := make([]string, 0, len(batch))
+			for _, evt := range batch {
+				keys = append(keys, evt.ObservedKey())
+			}
+			err = fmt.Errorf("%w: events still queued after %d flush rounds: %s", ErrMaxPropagationDepth, round, strings.Join(keys, ", "))
+			log.Print(err)
+			return err
+		}
+
+		for _, evt := range batch {
+			p := evt.Origin().propagation()
+			mark := p.errorMark()
+			evt.Origin().PropMutationHandlers.DispatchEvent(evt)
+			if perr := p.propagationError(mark); perr != nil && err == nil {
+				err = perr
+			}
+		}
+	}
+	return err
+}
+
+// SetScheduler attaches a Scheduler to the ElementStore so that the
+// MutationEvents of its Elements are dispatched asynchronously.
+// A nil Scheduler restores synchronous dispatch.
+func (e *ElementStore) SetScheduler(s *Scheduler) *ElementStore {
+	e.Scheduler = s
+	return e
+}
+
+// Flush dispatches the MutationEvents queued by the Scheduler of the
+// ElementStore, if any.
+func (e *ElementStore) Flush() error {
+	if e.Scheduler == nil {
+		return nil
+	}
+	return e.Scheduler.Flush()
+}
+
+// dispatchMutation dispatches a MutationEvent of the Element, or queues it if
+// the ElementStore of the Element has a Scheduler.
+func (e *Element) dispatchMutation(evt MutationEvent) {
+	if e.ElementStore != nil && e.ElementStore.Scheduler != nil {
+		e.ElementStore.Scheduler.schedule(evt)
+		return
+	}
+	e.PropMutationHandlers.DispatchEvent(evt)
+}
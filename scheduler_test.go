@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSchedulerFlushOrder(t *testing.T) {
+	s, thing := newTestStore("schedulerstore")
+	a := thing("a", "qa")
+	b := thing("b", "qb")
+
+	var got []string
+	record := NewMutationHandler(func(evt MutationEvent) bool {
+		got = append(got, fmt.Sprintf("%s=%v", evt.ObservedKey(), evt.NewValue()))
+		return false
+	})
+	a.WatchGroup("data", a, record)
+	b.WatchGroup("data", b, record)
+	// a handler queueing a mutation during the flush
+	b.Watch("data", "y", b, NewMutationHandler(func(evt MutationEvent) bool {
+		a.SetData("z", evt.NewValue())
+		return false
+	}))
+
+	var ticks []func()
+	s.SetScheduler(NewScheduler(func(flush func()) { ticks = append(ticks, flush) }))
+	a.SetData("x", Number(1))
+	b.SetData("y", Number(1))
+	a.SetData("x", Number(2))
+	if len(got) != 0 {
+		t.Fatalf("dispatched before flush: %v", got)
+	}
+	if n := s.Scheduler.Pending(); n != 2 {
+		t.Errorf("got %d pending events, want 2", n)
+	}
+	if len(ticks) != 1 {
+		t.Fatalf("got %d ticks, want 1", len(ticks))
+	}
+	ticks[0]()
+
+	want := []string{"qa/data/x=2", "qb/data/y=1", "qa/data/z=1"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	s.SetScheduler(nil)
+	got = nil
+	a.SetData("x", Number(3))
+	if len(got) != 1 {
+		t.Errorf("synchronous dispatch not restored: %v", got)
+	}
+}
+
+func TestSchedulerFlushLimit(t *testing.T) {
+	s, thing := newTestStore("schedulerlimit")
+	s.SetScheduler(NewScheduler(nil))
+	a := thing("a", "ql")
+	a.Watch("data", "n", a, NewMutationHandler(func(evt MutationEvent) bool {
+		a.SetData("n", evt.NewValue().(Number)+1)
+		return false
+	}))
+	a.SetData("n", Number(0))
+	if err := s.Flush(); !errors.Is(err, ErrMaxPropagationDepth) {
+		t.Errorf("got %v, want ErrMaxPropagationDepth", err)
+	}
+	if n := s.Scheduler.Pending(); n != 0 {
+		t.Errorf("got %d pending events after the limit, want 0", n)
+	}
+}
@@ -0,0 +1,220 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+var (
+	ErrSchemaViolation = errors.New("schema violation")
+)
+
+// PropertySchema describes the expected shape of an Element property.
+// An empty Type accepts any ValueType.
+type PropertySchema struct {
+	Category string
+	Name     string
+	Type     string
+
+	Required bool
+
+	Bounded  bool // if true, Number and Int values have to be within [Min, Max]
+	Min, Max float64
+
+	Enum []Value // if not empty, lists the only values accepted
+}
+
+// NewPropertySchema returns the schema of a property of the given ValueType.
+func NewPropertySchema(category string, propname string, valuetype string) PropertySchema {
+	return PropertySchema{Category: category, Name: propname, Type: valuetype}
+}
+
+// AsRequired makes the property mandatory: it has to be set by the end of the
+// Element construction and cannot be deleted.
+func (p PropertySchema) AsRequired() PropertySchema {
+	p.Required = true
+	return p
+}
+
+// Between restricts the numeric values accepted for the property to the
+// [min, max] interval.
+func (p PropertySchema) Between(min float64, max float64) PropertySchema {
+	p.Bounded = true
+	p.Min = min
+	p.Max = max
+	return p
+}
+
+// OneOf restricts the values accepted for the property to an enumeration.
+func (p PropertySchema) OneOf(values ...Value) PropertySchema {
+	p.Enum = values
+	return p
+}
+
+// Validate returns an error if the value does not conform to the property schema.
+func (p PropertySchema) Validate(value Value) error {
+	if value == nil {
+		return fmt.Errorf("%w: %s/%s cannot be nil", ErrSchemaViolation, p.Category, p.Name)
+	}
+	if p.Type != "" && value.ValueType() != p.Type {
+		return fmt.Errorf("%w: %s/%s expects a %s, got %s", ErrSchemaViolation, p.Category, p.Name, p.Type, value.ValueType())
+	}
+	if p.Bounded {
+		var n float64
+		switch t := value.(type) {
+		case Number:
+			n = float64(t)
+		case Int:
+			n = float64(t)
+		default:
+			return fmt.Errorf("%w: %s/%s expects a numeric value, got %s", ErrSchemaViolation, p.Category, p.Name, value.ValueType())
+		}
+		if n < p.Min || n > p.Max {
+			return fmt.Errorf("%w: %s/%s value %v is out of range [%v, %v]", ErrSchemaViolation, p.Category, p.Name, n, p.Min, p.Max)
+		}
+	}
+	if len(p.Enum) > 0 {
+		for _, v := range p.Enum {
+			if Equal(v, value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s/%s value %v is not one of %v", ErrSchemaViolation, p.Category, p.Name, value, p.Enum)
+	}
+	return nil
+}
+
+// Schema holds the property schemas that apply to the Elements created by a
+// given constructor.
+type Schema struct {
+	Properties map[string]PropertySchema // indexed by category/propname
+	Closed     map[string]bool           // categories in which undeclared properties are rejected
+}
+
+// NewSchema returns a Schema made of the given property schemas.
+func NewSchema(properties ...PropertySchema) Schema {
+	s := Schema{make(map[string]PropertySchema), make(map[string]bool)}
+	for _, p := range properties {
+		s.Properties[p.Category+"/"+p.Name] = p
+	}
+	return s
+}
+
+// Close makes the Schema reject any property of the given categories that has
+// not been declared. It helps catching misspelled property names.
+func (s Schema) Close(categories ...string) Schema {
+	for _, c := range categories {
+		s.Closed[c] = true
+	}
+	return s
+}
+
+// Validate returns an error if the property value does not conform to the Schema.
+func (s Schema) Validate(category string, propname string, value Value) error {
+	p, ok := s.Properties[category+"/"+propname]
+	if !ok {
+		if s.Closed[category] {
+			return fmt.Errorf("%w: %s/%s is not a declared property", ErrSchemaViolation, category, propname)
+		}
+		return nil
+	}
+	return p.Validate(value)
+}
+
+// WithSchema returns a ConstructorOption which, passed to
+// ElementStore.NewConstructor, registers the Schema that the properties of the
+// Elements created by the constructor have to conform to. Registering the
+// constructor again replaces its Schema.
+// Setting a non-conforming property is rejected and the error is returned by
+// Element.Set. When StrictValidation is enabled for the ElementStore, it
+// panics instead, which is mostly useful in tests.
+func WithSchema(s Schema) ConstructorOption {
+	return ConstructorOption{Name: "schema", schema: &s}
+}
+
+func (e *Element) schema() (Schema, bool) {
+	if e.ElementStore == nil || len(e.ElementStore.Schemas) == 0 {
+		return Schema{}, false
+	}
+	v, ok := e.Get("internals", "constructor")
+	if !ok {
+		return Schema{}, false
+	}
+	cname, ok := v.(String)
+	if !ok {
+		return Schema{}, false
+	}
+	s, ok := e.ElementStore.Schemas[string(cname)]
+	return s, ok
+}
+
+// validate checks a property value against the schema of the Element, if any.
+func (e *Element) validate(category string, propname string, value Value) error {
+	s, ok := e.schema()
+	if !ok {
+		return nil
+	}
+	err := s.Validate(category, propname, value)
+	if err != nil {
+		err = fmt.Errorf("Element %s: %w", e.ID, err)
+		e.ElementStore.reportSchemaViolation(err)
+	}
+	return err
+}
+
+// validateDeletion checks that a property is not required before it is deleted.
+func (e *Element) validateDeletion(category string, propname string) error {
+	s, ok := e.schema()
+	if !ok {
+		return nil
+	}
+	p, ok := s.Properties[category+"/"+propname]
+	if !ok || !p.Required {
+		return nil
+	}
+	err := fmt.Errorf("Element %s: %w: %s/%s is required and cannot be deleted", e.ID, ErrSchemaViolation, category, propname)
+	e.ElementStore.reportSchemaViolation(err)
+	return err
+}
+
+// validateProperties checks the properties of the Element against its Schema.
+// It is called once an Element has been constructed, since the properties set
+// before its constructor name is known cannot be validated by Set. It also
+// verifies that every required property has been set.
+func (e *Element) validateProperties() error {
+	s, ok := e.schema()
+	if !ok {
+		return nil
+	}
+	for category, ps := range e.Properties.Categories {
+		for _, group := range []map[string]Value{ps.Default, ps.Inherited, ps.Local, ps.Inheritable} {
+			for propname, value := range group {
+				if err := s.Validate(category, propname, value); err != nil {
+					err = fmt.Errorf("Element %s: %w", e.ID, err)
+					e.ElementStore.reportSchemaViolation(err)
+					return err
+				}
+			}
+		}
+	}
+	for _, p := range s.Properties {
+		if !p.Required {
+			continue
+		}
+		if !e.Properties.HasProperty(p.Category, p.Name) {
+			err := fmt.Errorf("Element %s: %w: required property %s/%s is missing", e.ID, ErrSchemaViolation, p.Category, p.Name)
+			e.ElementStore.reportSchemaViolation(err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *ElementStore) reportSchemaViolation(err error) {
+	if e.StrictValidation {
+		panic(err)
+	}
+	log.Print(err)
+}
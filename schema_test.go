@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+)
+
+func testSchema() Schema {
+	return NewSchema(
+		NewPropertySchema("data", "rows", "Number").Between(0, 100).AsRequired(),
+		NewPropertySchema("data", "mode", "String").OneOf(String("a"), String("b")),
+	).Close("data")
+}
+
+func TestSchemaValidation(t *testing.T) {
+	_, ctor := newTestStore("schemastore", WithSchema(testSchema()))
+	e := ctor("e", "schema-e")
+	if err := e.SetData("rows", Number(3)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		propname string
+		value    Value
+		valid    bool
+	}{
+		{"type mismatch", "rows", String("3"), false},
+		{"out of range", "rows", Number(300), false},
+		{"not in enum", "mode", String("c"), false},
+		{"in enum", "mode", String("a"), true},
+		{"undeclared property in closed category", "rowz", Number(3), false},
+	}
+	for _, test := range tests {
+		err := e.SetData(test.propname, test.value)
+		if test.valid && err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if !test.valid && !errors.Is(err, ErrSchemaViolation) {
+			t.Errorf("%s: got %v, want ErrSchemaViolation", test.name, err)
+		}
+	}
+	if v, _ := e.GetData("rows"); v != Number(3) {
+		t.Errorf("rejected value stored: got %v", v)
+	}
+	if err := e.Delete("data", "rows"); !errors.Is(err, ErrSchemaViolation) {
+		t.Errorf("deleting a required property: got %v, want ErrSchemaViolation", err)
+	}
+	if err := e.SetDataSyncUI("rows", Number(4)); err != nil {
+		t.Errorf("SetDataSyncUI: %v", err)
+	}
+}
+
+func TestSchemaRegisteredWithConstructor(t *testing.T) {
+	s, _ := newTestStore("schemactorstore", WithSchema(testSchema()))
+	s.NewConstructor("thing", func(name, id string) *Element { return NewElement(name, id, "test") })
+	e := s.Constructors["thing"]("e", "schemactor-e")
+	if err := e.SetData("rows", String("3")); err != nil {
+		t.Errorf("Schema kept after the constructor was registered again without it: %v", err)
+	}
+}
+
+func TestStrictValidation(t *testing.T) {
+	s, ctor := newTestStore("strictstore", WithSchema(testSchema()))
+	withRows := s.NewConstructor("withrows", func(name, id string) *Element {
+		e := NewElement(name, id, "test")
+		e.SetData("rows", Number(1))
+		return e
+	}, WithSchema(testSchema()))
+	s.StrictValidation = true
+
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{"missing required property", func() { ctor("e", "strict-missing") }},
+		{"type mismatch", func() { withRows("e", "strict-mismatch").SetData("rows", Bool(true)) }},
+	}
+	for _, test := range tests {
+		func() {
+			defer func() {
+				r := recover()
+				if err, ok := r.(error); !ok || !errors.Is(err, ErrSchemaViolation) {
+					t.Errorf("%s: got %v, want a ErrSchemaViolation panic", test.name, r)
+				}
+			}()
+			test.fn()
+		}()
+	}
+}
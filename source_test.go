@@ -0,0 +1,96 @@
+package ui
+
+import "testing"
+
+// lastEvent watches a property of e and returns a pointer to the last
+// MutationEvent dispatched for it.
+func lastEvent(e *Element, category string, propname string) *MutationEvent {
+	var last MutationEvent
+	e.Watch(category, propname, e, NewMutationHandler(func(evt MutationEvent) bool {
+		last = evt
+		return false
+	}))
+	return &last
+}
+
+func TestMutationParentID(t *testing.T) {
+	_, thing := newTestStore("parentstore")
+	a := thing("a", "parenta")
+	b := thing("b", "parentb")
+	ea := lastEvent(a, "data", "x")
+	eb := lastEvent(b, "data", "y")
+	a.Watch("data", "x", a, NewMutationHandler(func(evt MutationEvent) bool {
+		b.SetData("y", evt.NewValue())
+		return false
+	}))
+
+	a.SetData("x", Number(1))
+	if (*ea).ID() == "" || (*ea).ParentID() != "" {
+		t.Errorf("top-level mutation: got ID %q and parent ID %q", (*ea).ID(), (*ea).ParentID())
+	}
+	if (*eb).ParentID() != (*ea).ID() {
+		t.Errorf("nested mutation: got parent ID %q, want %q", (*eb).ParentID(), (*ea).ID())
+	}
+	if (*eb).ID() == (*ea).ID() {
+		t.Error("mutations share the same ID")
+	}
+	if (*eb).Source() != SourceProgram {
+		t.Errorf("nested mutation: got source %q, want %q", (*eb).Source(), SourceProgram)
+	}
+
+	b.SetData("y", Number(2))
+	if (*eb).ParentID() != "" {
+		t.Errorf("parent ID %q leaked to a later mutation", (*eb).ParentID())
+	}
+}
+
+func TestMutationSources(t *testing.T) {
+	s, thing := newTestStore("sourcestore")
+	e := thing("e", "source")
+	child := thing("child", "sourcechild")
+
+	tests := []struct {
+		name     string
+		category string
+		propname string
+		mutate   func()
+		want     MutationSource
+	}{
+		{"program", "data", "x", func() { e.SetData("x", Number(1)) }, SourceProgram},
+		{"user input", "data", "input", func() { e.SyncUISetData("input", String("typed")) }, SourceUserInput},
+		{"load", "ui", "loaded", func() { LoadProperty(e, "ui", "loaded", "Local", Number(1)) }, SourceLoad},
+		{"store scope", "data", "scoped", func() {
+			defer s.EnterMutationSource(SourceRouter)()
+			e.SetData("scoped", Number(1))
+		}, SourceRouter},
+	}
+	for _, tt := range tests {
+		last := lastEvent(e, tt.category, tt.propname)
+		tt.mutate()
+		if *last == nil {
+			t.Fatalf("%s: no MutationEvent dispatched", tt.name)
+		}
+		if got := (*last).Source(); got != tt.want {
+			t.Errorf("%s: got source %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// The mutations made while executing a Command are caused by the command
+	// mutation.
+	command := lastEvent(e, "ui", "command")
+	attached := lastEvent(child, "event", "attached")
+	e.Mutate(AppendChildCommand(child))
+	if *attached == nil {
+		t.Fatal("command: no MutationEvent dispatched")
+	}
+	if (*attached).Source() != SourceCommand || (*attached).ParentID() != (*command).ID() {
+		t.Errorf("command: got source %q and parent ID %q, want %q and %q", (*attached).Source(), (*attached).ParentID(), SourceCommand, (*command).ID())
+	}
+
+	// Source scopes do not outlive the mutations they apply to.
+	last := lastEvent(e, "data", "after")
+	e.SetData("after", Number(1))
+	if (*last).Source() != SourceProgram || (*last).ParentID() != "" {
+		t.Errorf("scope leaked: got source %q and parent ID %q", (*last).Source(), (*last).ParentID())
+	}
+}
@@ -0,0 +1,91 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+// Subscription represents the registration of a handler by an Element, be it
+// a MutationHandler watching a property or an EventHandler listening to an
+// event. Cancelling a Subscription unregisters the handler.
+//
+// Subscriptions are tracked by the subscribing Element and by the Element being
+// observed: they are cancelled automatically when either of them is removed
+// from its ElementStore.
+type Subscription struct {
+	subscriber *Element
+	target     *Element
+	key        string      // observed property address or event name
+	handler    interface{} // *MutationHandler or *EventHandler
+	cancel     func()
+	active     bool
+	bridge     NativeEventBridge // native binding of an event listener, if any
+}
+
+func newSubscription(subscriber *Element, target *Element, key string, handler interface{}, cancel func()) *Subscription {
+	s := &Subscription{subscriber, target, key, handler, cancel, true, nil}
+	subscriber.addSubscription(s)
+	target.addSubscription(s)
+	return s
+}
+
+// Cancel unregisters the handler. Calling Cancel more than once has no effect.
+func (s *Subscription) Cancel() {
+	if s == nil || !s.active {
+		return
+	}
+	s.active = false
+	s.cancel()
+	delete(s.subscriber.subscriptions, s)
+	delete(s.target.subscriptions, s)
+}
+
+// Active reports whether the Subscription has not been cancelled yet.
+func (s *Subscription) Active() bool {
+	return s != nil && s.active
+}
+
+// Subscriber returns the Element which registered the handler.
+func (s *Subscription) Subscriber() *Element { return s.subscriber }
+
+// Target returns the Element being observed.
+func (s *Subscription) Target() *Element { return s.target }
+
+func (e *Element) addSubscription(s *Subscription) {
+	if e.subscriptions == nil {
+		e.subscriptions = make(map[*Subscription]struct{})
+	}
+	e.subscriptions[s] = struct{}{}
+}
+
+// cancelSubscriptions cancels the Subscriptions for which match returns true.
+func (e *Element) cancelSubscriptions(match func(*Subscription) bool) {
+	var l []*Subscription
+	for s := range e.subscriptions {
+		if match(s) {
+			l = append(l, s)
+		}
+	}
+	for _, s := range l {
+		s.Cancel()
+	}
+}
+
+// dispose releases every Subscription and computation the Element is involved in.
+func (e *Element) dispose() {
+	for _, c := range e.computed {
+		e.Uncompute(c.category, c.propname)
+	}
+	e.cancelSubscriptions(func(*Subscription) bool { return true })
+}
+
+// RemoveByID removes an Element from the ElementStore.
+// The Subscriptions the Element is involved in, either as a subscriber or as
+// the observed target, are cancelled so that no handler outlives it.
+func (e *ElementStore) RemoveByID(id string) *ElementStore {
+	element, ok := e.ByID[id]
+	if !ok {
+		return e
+	}
+	delete(e.ByID, id)
+	// dispatched synchronously since the handlers are about to be released
+	element.set("event", "disposed", Bool(true), dispatchNow(element.PropMutationHandlers.DispatchEvent))
+	element.dispose()
+	return e
+}
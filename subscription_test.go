@@ -0,0 +1,101 @@
+package ui
+
+import "testing"
+
+func TestSubscriptionCancel(t *testing.T) {
+	_, ctor := newTestStore("cancelstore")
+	a := ctor("a", "cancel-a")
+	b := ctor("b", "cancel-b")
+	var got []string
+	sub := b.Watch("data", "x", a, recorder(&got, "x"))
+	if sub.Subscriber() != b || sub.Target() != a || !sub.Active() {
+		t.Fatal("wrong Subscription")
+	}
+	a.SetData("x", Number(1))
+	sub.Cancel()
+	sub.Cancel()
+	a.SetData("x", Number(2))
+	if len(got) != 1 || sub.Active() {
+		t.Errorf("handler called %d times, want 1", len(got))
+	}
+	if l := a.Properties.Categories["data"].Watchers["x"]; len(l.List) != 0 {
+		t.Error("watcher still registered after Cancel")
+	}
+
+	clicks := 0
+	es := a.AddEventListener("click", NewEventHandler(func(Event) bool {
+		clicks++
+		return false
+	}), nil)
+	es.Cancel()
+	a.DispatchEvent(NewEvent("click", false, false, a, nil, ""), nil)
+	if clicks != 0 || a.EventHandlers.hasHandlers("click") {
+		t.Error("event handler still registered after Cancel")
+	}
+}
+
+func TestUnwatch(t *testing.T) {
+	_, ctor := newTestStore("unwatchstore")
+	a := ctor("a", "unwatch-a")
+	b := ctor("b", "unwatch-b")
+	var got []string
+	a.Watch("data", "x", a, recorder(&got, "a"))
+	b.Watch("data", "x", a, recorder(&got, "b"))
+	b.Watch("data", "y", a, recorder(&got, "b/y"))
+
+	a.Unwatch("data", "x", a)
+	a.SetData("x", Number(1))
+	if len(got) != 1 || got[0] != "b" {
+		t.Errorf("got %v, want [b]: Unwatch cancelled another subscriber's watcher", got)
+	}
+
+	got = nil
+	b.Unwatch("data", "x", a)
+	a.SetData("x", Number(2))
+	a.SetData("y", Number(2))
+	if len(got) != 1 || got[0] != "b/y" {
+		t.Errorf("got %v, want [b/y]", got)
+	}
+}
+
+func TestRemoveByID(t *testing.T) {
+	s, ctor := newTestStore("removestore")
+	a := ctor("a", "remove-a")
+	b := ctor("b", "remove-b")
+	c := ctor("c", "remove-c")
+	var got []string
+	b.Watch("data", "x", a, recorder(&got, "b watches a"))
+	a.Watch("data", "x", c, recorder(&got, "a watches c"))
+	a.Watch("event", "disposed", a, recorder(&got, "disposed"))
+	clicks := 0
+	a.AddEventListener("click", NewEventHandler(func(Event) bool {
+		clicks++
+		return false
+	}), nil)
+
+	s.RemoveByID(a.ID)
+	if len(got) != 1 || got[0] != "disposed" {
+		t.Fatalf("got %v, want the disposed notification", got)
+	}
+	if s.GetByID(a.ID) != nil {
+		t.Error("Element still registered in the ElementStore")
+	}
+
+	got = nil
+	a.SetData("x", Number(1))
+	c.SetData("x", Number(1))
+	a.DispatchEvent(NewEvent("click", false, false, a, nil, ""), nil)
+	if len(got) != 0 || clicks != 0 {
+		t.Errorf("handlers called after disposal: %v, %d clicks", got, clicks)
+	}
+	if len(a.subscriptions) != 0 {
+		t.Errorf("%d Subscriptions left after disposal", len(a.subscriptions))
+	}
+	for _, e := range []*Element{b, c} {
+		for sub := range e.subscriptions {
+			if sub.subscriber == a || sub.target == a {
+				t.Errorf("Subscription %s left on %s", sub.key, e.ID)
+			}
+		}
+	}
+}
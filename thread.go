@@ -0,0 +1,101 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+var (
+	ErrUIWorkPanicked = errors.New("UI work panicked")
+)
+
+// Concurrency model
+//
+// Elements, their properties and their handlers are not safe for concurrent
+// use. Their state is meant to be accessed from a single logical UI thread.
+// Goroutines that need to update the UI, e.g. when receiving data from the
+// network, have to submit their work via ElementStore.Do.
+//
+// Work submitted via Do is run sequentially, in submission order, and never
+// concurrently with other submitted work, whichever the ElementStore. Drivers
+// for platforms on which UI callbacks may run concurrently with other
+// goroutines should submit those callbacks via Do as well.
+
+// uiThread serializes the execution of the functions submitted via Do.
+// The goroutine which submits work while no other work is running executes
+// the queued functions until none remains. Other goroutines merely enqueue.
+type uiThread struct {
+	mu      sync.Mutex
+	queue   []uiWork
+	running bool
+}
+
+// uiWork is a function submitted via Do along with the channel on which the
+// outcome of its execution is reported.
+type uiWork struct {
+	fn   func()
+	done chan error
+}
+
+var uithread = &uiThread{}
+
+func (t *uiThread) do(fn func()) <-chan error {
+	w := uiWork{fn, make(chan error, 1)}
+	t.mu.Lock()
+	t.queue = append(t.queue, w)
+	if t.running {
+		t.mu.Unlock()
+		return w.done
+	}
+	t.running = true
+	t.mu.Unlock()
+
+	for {
+		t.mu.Lock()
+		if len(t.queue) == 0 {
+			t.running = false
+			t.mu.Unlock()
+			return w.done
+		}
+		next := t.queue[0]
+		t.queue[0] = uiWork{}
+		t.queue = t.queue[1:]
+		t.mu.Unlock()
+
+		next.done <- run(next.fn)
+		close(next.done)
+	}
+}
+
+// run executes fn. A panic is recovered and returned as an error so that it
+// neither unwinds the goroutine draining the queue, which may not be the one
+// which submitted fn, nor leaves the remaining work queued.
+func run(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrUIWorkPanicked, r)
+			log.Print(err)
+		}
+	}()
+	fn()
+	return nil
+}
+
+// Do submits fn for execution on the UI thread.
+// It can be called from any goroutine. If no UI work is in progress, fn is
+// executed before Do returns. Otherwise, it is queued and executed once the
+// work submitted before it has completed. In particular, calling Do from
+// within a function submitted via Do does not block: the nested function is
+// run after the current one.
+//
+// The returned channel receives the outcome of the execution of fn and is then
+// closed: nil, or an error wrapping ErrUIWorkPanicked if fn panicked. Receiving
+// from it waits for fn to have run. It must not be waited on from within a
+// function submitted via Do, since the nested function cannot run before the
+// current one returns.
+func (e *ElementStore) Do(fn func()) <-chan error {
+	return uithread.do(fn)
+}
@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentDo(t *testing.T) {
+	s, thing := newTestStore("threadstore")
+	var root *Element
+	<-s.Do(func() { root = thing("root", "threadroot") })
+
+	var count int
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := <-s.Do(func() {
+				c := thing("c", NewIDgenerator(int64(i))())
+				c.Watch("data", "n", root, NewMutationHandler(func(evt MutationEvent) bool {
+					count++
+					return false
+				}))
+				root.AppendChild(c)
+				root.SetData("n", Number(i))
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	<-s.Do(func() {
+		if n := len(root.Children.List); n != 100 {
+			t.Errorf("got %d children, want 100", n)
+		}
+		if count == 0 {
+			t.Error("watchers were not notified")
+		}
+	})
+}
+
+func TestDoPanic(t *testing.T) {
+	s := NewElementStore("panicstore", "test")
+	var after bool
+	var nested <-chan error
+	err := <-s.Do(func() {
+		nested = s.Do(func() { after = true })
+		panic("boom")
+	})
+	if !errors.Is(err, ErrUIWorkPanicked) {
+		t.Errorf("got %v, want ErrUIWorkPanicked", err)
+	}
+	if err := <-nested; err != nil || !after {
+		t.Errorf("work queued behind a panicking function did not run: %v", err)
+	}
+	if err := <-s.Do(func() {}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,192 @@
+// Package ui is a library of functions for simple, generic gui development.
+package ui
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrTxDone         = errors.New("transaction has already been committed or rolled back")
+	ErrTxForeignStore = errors.New("Element does not belong to the transaction's ElementStore")
+)
+
+// Tx buffers property mutations so that they can be applied at once.
+// Mutations are only applied when the transaction commits. The MutationEvents
+// they generate are then coalesced: a single event is dispatched per property,
+// holding the last value that was buffered for it.
+// A Tx is obtained via Element.Batch or ElementStore.Transaction.
+type Tx struct {
+	store *ElementStore // if not nil, restricts the Elements that can be mutated
+	ops   []*txOp
+	index map[string]*txOp
+	done  bool
+}
+
+type txOp struct {
+	element  *Element
+	category string
+	propname string
+	value    Value
+	flags    []bool
+	delete   bool
+}
+
+func newTx(store *ElementStore) *Tx {
+	return &Tx{store, nil, make(map[string]*txOp), false}
+}
+
+func txKey(e *Element, category string, propname string) string {
+	return e.ID + "/" + category + "/" + propname
+}
+
+func (tx *Tx) check(e *Element) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	if e == nil {
+		return errors.New("nil Element")
+	}
+	if tx.store != nil && e.ElementStore != tx.store {
+		return fmt.Errorf("%w: %s", ErrTxForeignStore, e.ID)
+	}
+	return nil
+}
+
+func (tx *Tx) buffer(op *txOp) {
+	key := txKey(op.element, op.category, op.propname)
+	if prev, ok := tx.index[key]; ok {
+		*prev = *op // last value wins, the position of the first mutation is kept
+		return
+	}
+	tx.index[key] = op
+	tx.ops = append(tx.ops, op)
+}
+
+// Set buffers the mutation of a property of an Element.
+// The value is passed through the interceptors of the property and validated
+// against the Element schema right away so that a vetoed or invalid value can
+// be handled before the transaction commits.
+func (tx *Tx) Set(e *Element, category string, propname string, value Value, flags ...bool) error {
+	if err := tx.check(e); err != nil {
+		return err
+	}
+	value, err := e.intercept(category, propname, value)
+	if err != nil {
+		return err
+	}
+	if err := e.validate(category, propname, value); err != nil {
+		return err
+	}
+	tx.buffer(&txOp{e, category, propname, value, flags, false})
+	return nil
+}
+
+// Delete buffers the deletion of a property of an Element.
+func (tx *Tx) Delete(e *Element, category string, propname string) error {
+	if err := tx.check(e); err != nil {
+		return err
+	}
+	if err := e.validateDeletion(category, propname); err != nil {
+		return err
+	}
+	tx.buffer(&txOp{e, category, propname, nil, nil, true})
+	return nil
+}
+
+// Get retrieves the value of a property of an Element as seen from within the
+// transaction, i.e. taking buffered mutations into account.
+func (tx *Tx) Get(e *Element, category string, propname string) (Value, bool) {
+	if op, ok := tx.index[txKey(e, category, propname)]; ok {
+		if op.delete {
+			return nil, false
+		}
+		return op.value, true
+	}
+	return e.Get(category, propname)
+}
+
+// rollback discards the buffered mutations.
+func (tx *Tx) rollback() {
+	tx.ops = nil
+	tx.index = make(map[string]*txOp)
+	tx.done = true
+}
+
+// commit applies the buffered mutations in the order in which the properties
+// were first mutated, then dispatches the coalesced MutationEvents. Changes
+// of inheritable properties are propagated to the descendants once the event
+// of the Element they originate from has been dispatched.
+func (tx *Tx) commit() error {
+	tx.done = true
+
+	events := make([]MutationEvent, 0, len(tx.ops))
+	pos := make(map[string]int)
+	inheritable := make(map[string]bool)
+	collect := func(evt MutationEvent, inh bool) {
+		key := evt.ObservedKey()
+		inheritable[key] = inheritable[key] || inh
+		if i, ok := pos[key]; ok {
+			events[i] = coalesce(events[i], evt)
+			return
+		}
+		pos[key] = len(events)
+		events = append(events, evt)
+	}
+
+	var err error
+	for _, op := range tx.ops {
+		var oerr error
+		if op.delete {
+			oerr = op.element.delete(op.category, op.propname, collect)
+		} else {
+			oerr = op.element.store(op.category, op.propname, op.value, collect, op.flags...)
+		}
+		if oerr != nil && err == nil {
+			err = oerr
+		}
+	}
+
+	for _, evt := range events {
+		p := evt.Origin().propagation()
+		mark := p.errorMark()
+		evt.Origin().dispatchMutation(evt)
+		if inheritable[evt.ObservedKey()] {
+			evt.Origin().propagateToDescendants(evt)
+		}
+		if perr := p.propagationError(mark); perr != nil && err == nil {
+			err = perr
+		}
+	}
+	return err
+}
+
+func runTx(tx *Tx, fn func(tx *Tx) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			tx.rollback()
+			panic(r)
+		}
+	}()
+	if err = fn(tx); err != nil {
+		tx.rollback()
+		return err
+	}
+	return tx.commit()
+}
+
+// Batch runs fn within a transaction restricted to the Elements of the
+// ElementStore the Element belongs to. The mutations buffered by fn are
+// committed once it returns, each modified property triggering the dispatch of
+// a single MutationEvent.
+// If fn returns an error or panics, the buffered mutations are discarded and
+// the Element properties are left untouched.
+func (e *Element) Batch(fn func(tx *Tx) error) error {
+	return runTx(newTx(e.ElementStore), fn)
+}
+
+// Transaction runs fn within a transaction restricted to the Elements of the
+// ElementStore. See Element.Batch.
+func (e *ElementStore) Transaction(fn func(tx *Tx) error) error {
+	return runTx(newTx(e), fn)
+}
@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBatchCoalescing(t *testing.T) {
+	_, ctor := newTestStore("batchstore")
+	e := ctor("e", "batch-e")
+	var got []Value
+	watch := func(propname string) {
+		e.Watch("data", propname, e, NewMutationHandler(func(evt MutationEvent) bool {
+			got = append(got, evt.NewValue())
+			return false
+		}))
+	}
+	watch("a")
+	watch("b")
+
+	err := e.Batch(func(tx *Tx) error {
+		tx.Set(e, "data", "a", Number(1))
+		tx.Set(e, "data", "b", String("x"))
+		tx.Set(e, "data", "a", Number(2))
+		if v, _ := tx.Get(e, "data", "a"); v != Number(2) {
+			t.Errorf("Tx.Get: got %v, want the buffered value", v)
+		}
+		if _, ok := e.GetData("a"); ok {
+			t.Error("mutation applied before commit")
+		}
+		if len(got) != 0 {
+			t.Errorf("dispatched before commit: %v", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Value{Number(2), String("x")}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %v, want a single dispatch per property: %v", got, want)
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	s, ctor := newTestStore("rollbackstore")
+	e := ctor("e", "rollback-e")
+	e.SetData("a", Number(1))
+	var got []string
+	e.Watch("data", "a", e, recorder(&got, "a"))
+
+	tests := []struct {
+		name string
+		fn   func(tx *Tx) error
+	}{
+		{"error", func(tx *Tx) error {
+			tx.Set(e, "data", "a", Number(2))
+			tx.Delete(e, "data", "a")
+			return errors.New("failed")
+		}},
+		{"panic", func(tx *Tx) error {
+			tx.Set(e, "data", "a", Number(3))
+			panic("failed")
+		}},
+	}
+	for _, test := range tests {
+		func() {
+			defer func() { recover() }()
+			if err := s.Transaction(test.fn); err == nil {
+				t.Errorf("%s: got no error", test.name)
+			}
+		}()
+		if v, _ := e.GetData("a"); v != Number(1) || len(got) != 0 {
+			t.Errorf("%s: got %v after %d dispatches, want the value before the transaction", test.name, v, len(got))
+		}
+	}
+
+	other := NewElementStore("rollbackother", "test")
+	o := NewElement("o", "rollback-o", "test")
+	o.ElementStore = other
+	s.Transaction(func(tx *Tx) error {
+		if err := tx.Set(o, "data", "a", Number(1)); !errors.Is(err, ErrTxForeignStore) {
+			t.Errorf("got %v, want ErrTxForeignStore", err)
+		}
+		return nil
+	})
+}
@@ -2,8 +2,10 @@
 package ui
 
 import (
-	"time"
+	"encoding/base64"
 	"log"
+	"strconv"
+	"time"
 	//"strings"
 )
 type MutationRecord Object
@@ -17,7 +19,7 @@ func NewMutationRecord(category string, propname string, value Value) MutationRe
 	mr.Set("category", String(category))
 	mr.Set("property", String(propname))
 	mr.Set("value", value)
-	mr.Set("timestamp", String(time.Now().UTC().String()))
+	mr.Set("timestamp", Time(time.Now().UTC()))
 
 	return MutationRecord(mr)
 }
@@ -25,12 +27,35 @@ func NewMutationRecord(category string, propname string, value Value) MutationRe
 type discriminant string // just here to pin the definition of the Value interface to this package
 
 // Value is the type for Element property values.
+//
+// Types defined outside of this package can implement Value by embedding
+// CustomValue. Their RawValue should return an Object whose "typ" field
+// holds their ValueType so that they can be decoded back once a decoding
+// function has been registered via RegisterValueType.
 type Value interface {
 	discriminant() discriminant
 	RawValue() Object
 	ValueType() string
 }
 
+// CustomValue is meant to be embedded in application-defined types so that
+// they may implement the Value interface.
+//  type Money struct {
+//  	ui.CustomValue
+//  	Amount   int64
+//  	Currency string
+//  }
+type CustomValue struct{}
+
+func (c CustomValue) discriminant() discriminant { return "particleui" }
+func (c CustomValue) custom()                    {}
+
+// customValue is implemented by the Values embedding CustomValue.
+type customValue interface {
+	Value
+	custom()
+}
+
 func (e *Element) discriminant() discriminant { return "particleui" }
 func (e *Element) ValueType() string          { return "Element" }
 func (e *Element) RawValue() Object {
@@ -43,7 +68,7 @@ func (e *Element) RawValue() Object {
 		o.Set("constructoroptions", constructoroptions)
 	}
 
-	constructorname, ok := e.Get("internals", "constructorname")
+	constructorname, ok := e.Get("internals", "constructor")
 	if !ok {
 		return nil
 	}
@@ -53,6 +78,9 @@ func (e *Element) RawValue() Object {
 	}
 	o["constructorname"] = cname
 
+	if e.ElementStore == nil {
+		return nil
+	}
 	o["elementstoreid"] = String(e.ElementStore.Global.ID)
 	return o.RawValue()
 }
@@ -90,6 +118,56 @@ func (n Number) RawValue() Object {
 }
 func (n Number) ValueType() string { return "Number" }
 
+// Int is an integer Value. Unlike Number, it does not lose precision for large
+// values such as IDs or counters.
+type Int int64
+
+func (i Int) discriminant() discriminant { return "particleui" }
+func (i Int) RawValue() Object {
+	o := NewObject()
+	o["typ"] = "Int"
+	o["value"] = strconv.FormatInt(int64(i), 10) // JSON numbers would not preserve 64bit precision
+	return o.RawValue()
+}
+func (i Int) ValueType() string { return "Int" }
+
+// Bytes is a Value holding binary data.
+type Bytes []byte
+
+func (b Bytes) discriminant() discriminant { return "particleui" }
+func (b Bytes) RawValue() Object {
+	o := NewObject()
+	o["typ"] = "Bytes"
+	o["value"] = base64.StdEncoding.EncodeToString(b)
+	return o.RawValue()
+}
+func (b Bytes) ValueType() string { return "Bytes" }
+
+// Time is a Value holding a point in time. It is encoded following RFC 3339.
+type Time time.Time
+
+func (t Time) discriminant() discriminant { return "particleui" }
+func (t Time) RawValue() Object {
+	o := NewObject()
+	o["typ"] = "Time"
+	o["value"] = time.Time(t).Format(time.RFC3339Nano)
+	return o.RawValue()
+}
+func (t Time) ValueType() string { return "Time" }
+
+// NullValue is the type of Null.
+type NullValue struct{}
+
+// Null is the Value denoting the absence of value. For instance, it is the
+// new value of the MutationEvent dispatched when a property is deleted.
+var Null = NullValue{}
+
+func (n NullValue) discriminant() discriminant { return "particleui" }
+func (n NullValue) RawValue() Object {
+	return NewObject().SetType("Null")
+}
+func (n NullValue) ValueType() string { return "Null" }
+
 type Object map[string]interface{}
 
 func (o Object) discriminant() discriminant { return "particleui" }
@@ -132,227 +210,17 @@ func (o Object) SetType(typ string) Object {
 	o["typ"] = typ
 	return o
 }
-func (o Object) Value() Value {
-	switch o.ValueType() {
-	case "Bool":
-		v, ok := o.Get("value")
-		if !ok {
-			return nil
-		}
-		res, ok := v.(bool)
-		if !ok {
-			return nil
-		}
-		return Bool(res)
-	case "String":
-		v, ok := o.Get("value")
-		if !ok {
-			return nil
-		}
-		res, ok := v.(string)
-		if !ok {
-			return nil
-		}
-		return String(res)
-	case "Number":
-		v, ok := o.Get("value")
-		if !ok {
-			return nil
-		}
-		res, ok := v.(float64)
-		if !ok {
-			return nil
-		}
-		return Number(res)
-	case "List":
-		v, ok := o.Get("value")
-		if !ok {
-			return nil
-		}
-		l, ok := v.([]interface{})
-		if !ok {
-			return nil
-		}
-		m := NewList()
-		for _, val := range l {
-			r, ok := val.(map[string]interface{})
-			if ok {
-				v := Object(r).Value()
-				m = append(m, v)
-				continue
-			} else {
-				return nil
-			}
-		}
-		return m
-	case "Object":
-		p := NewObject()
-		for k, val := range o {
-			v, ok := val.(Value)
-			if !ok {
-				m, ok := val.(map[string]interface{})
-				if ok {
-					obj := Object(m)
-					p.Set(k, obj.Value())
-					continue
-				}
-				p[k] = val
-				continue
-			}
-			u, ok := v.(Object)
-			if !ok {
-				p.Set(k, v)
-				continue
-			}
-			m, ok := val.(map[string]interface{})
-			if ok {
-				obj := Object(m)
-				p.Set(k, obj.Value())
-			}
-			p.Set(k, u.Value())
-		}
-		return p
-	case "Command":
-		p := NewObject()
-		for k, val := range o {
-			v, ok := val.(Value)
-			if !ok {
-				m, ok := val.(map[string]interface{})
-				if ok {
-					obj := Object(m)
-					p.Set(k, obj.Value())
-					continue
-				}
-				p[k] = val
-				continue
-			}
-			u, ok := v.(Object)
-			if ok {
-				p.Set(k, u.Value())
-				continue
-			}
-			p.Set(k, v)
-		}
-		return Command(p)
-	case "MutationRecord":
-		p := NewObject()
-		for k, val := range o {
-			v, ok := val.(Value)
-			if !ok {
-				m, ok := val.(map[string]interface{})
-				if ok {
-					obj := Object(m)
-					p.Set(k, obj.Value())
-					continue
-				}
-				p[k] = val
-				continue
-			}
-			u, ok := v.(Object)
-			if ok {
-				p.Set(k, u.Value())
-				continue
-			}
-			p.Set(k, v)
-		}
-		return MutationRecord(p)
-	case "Element":
-		p := NewObject()
-		for k, val := range o {
-			v, ok := val.(Value)
-			if !ok {
-				m, ok := val.(map[string]interface{})
-				if ok {
-					obj := Object(m)
-					p.Set(k, obj.Value())
-					continue
-				}
-				p[k] = val
-				continue
-			}
-			u, ok := v.(Object)
-			if ok {
-				p.Set(k, u.Value())
-				continue
-			}
-			p.Set(k, v)
-		}
 
-		id, ok := p.Get("id")
-		if !ok {
-			return nil
-		}
-		name, ok := p.Get("name")
-		if !ok {
-			return nil
-		}
-		elementstoreid, ok := p.Get("elementstoreid")
-		if !ok {
-			return nil
-		}
-		constructorname, ok := p.Get("constructorname")
-		if !ok {
-			return nil
-		}
-		elstoreid, ok := elementstoreid.(String)
-		if !ok {
-			log.Print("Wrong type for ElementStore ID")
-			return nil
-		}
-		// Let's get the elementstore
-		elstore, ok := Stores.Get(string(elstoreid))
-		if !ok {
-			return nil
-		}
-		// Let's try to see if the element is in the ElementStore already
-		elid, ok := id.(String)
-		if !ok {
-			log.Print("Wrong type for Element ID stored in ui.Value")
-			return nil
-		}
-		element := elstore.GetByID(string(elid))
-		if element != nil {
-			return element
-		}
-		// Otherwise we construct it. (TODO: make sure that element constructors try to get the data in store)
-		cname, ok := constructorname.(String)
-		if !ok {
-			log.Print("Wrong type for constructor name.")
-			return nil
-		}
-		constructor, ok := elstore.Constructors[string(cname)]
-		if !ok {
-			log.Print("constructor not found at thhe recorded name from Element store. Cannot create Element " + elid + "from Value")
-		}
-		ename, ok := name.(String)
-		if !ok {
-			log.Print("Element name in Value of wring type.")
-			return nil
-		}
-
-		coptions := make([]string, 0)
-		constructoroptions, ok := p.Get("constructoroptions")
-		if ok {
-			objoptlist, ok := constructoroptions.(Object)
-			if ok {
-				voptlist := objoptlist.Value()
-				optlist, ok := voptlist.(List)
-				if ok {
-					for _, opt := range optlist {
-						sopt, ok := opt.(String)
-						if !ok {
-							return nil
-						}
-						coptions = append(coptions, string(sopt))
-					}
-				}
-			}
-		}
-		return constructor(string(ename), string(elid), coptions...)
-
-	default:
-		return o
+// Value returns the Value that an Object holding a raw representation stands for.
+// It returns nil if the Object cannot be decoded. DecodeValue should be used
+// instead when the decoding error matters.
+func (o Object) Value() Value {
+	v, err := DecodeValue(o)
+	if err != nil {
+		log.Print(err)
+		return nil
 	}
+	return v
 }
 
 func NewObject() Object {
@@ -369,6 +237,9 @@ func (l List) RawValue() Object {
 
 	raw := make([]interface{}, 0)
 	for _, v := range l {
+		if v == nil {
+			v = Null // a nil item has no raw representation of its own
+		}
 		raw = append(raw, v.RawValue())
 	}
 	o["value"] = raw
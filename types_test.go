@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValueTypesRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		v    Value
+		raw  string // expected raw "value" field
+	}{
+		{"Int beyond 2^53", Int(1<<53 + 1), "9007199254740993"},
+		{"negative Int", Int(-1<<62 - 3), "-4611686018427387907"},
+		{"Bytes", Bytes{0, 1, 254, 255}, "AAH+/w=="},
+		{"Time", Time(time.Date(2021, 3, 4, 5, 6, 7, 123456789, time.FixedZone("", 3600))), "2021-03-04T05:06:07.123456789+01:00"},
+	}
+	for _, test := range tests {
+		if raw, _ := test.v.RawValue().Get("value"); raw != test.raw {
+			t.Errorf("%s: got raw value %v, want %s", test.name, raw, test.raw)
+		}
+		b, err := MarshalValue(test.v)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		r, err := UnmarshalValue(b)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !Equal(r, test.v) {
+			t.Errorf("%s: got %#v, want %#v", test.name, r, test.v)
+		}
+	}
+}
+
+func TestNullListItem(t *testing.T) {
+	b, err := MarshalValue(NewList(String("a"), nil, Null))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := UnmarshalValue(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := NewList(String("a"), Null, Null); !Equal(r, want) {
+		t.Errorf("got %#v, want %#v", r, want)
+	}
+}
+
+func TestDeleteDispatchesNull(t *testing.T) {
+	_, ctor := newTestStore("nullstore")
+	e := ctor("e", "null-e")
+	var got []Value
+	e.Watch("data", "p", e, NewMutationHandler(func(evt MutationEvent) bool {
+		got = append(got, evt.NewValue())
+		return false
+	}))
+	e.SetData("p", Int(1))
+	e.Delete("data", "p")
+	if len(got) != 2 || got[1] != Null {
+		t.Errorf("got %v, want Null as the new value on delete", got)
+	}
+}
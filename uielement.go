@@ -7,6 +7,7 @@ import (
 	"log"
 	"math/rand"
 	"strings"
+	"sync"
 )
 
 var (
@@ -35,19 +36,24 @@ var Stores = newElementStores()
 
 type elementStores struct {
 	stores map[string]*ElementStore
+	mu     *sync.RWMutex
 }
 
 func newElementStores() elementStores {
 	v := make(map[string]*ElementStore)
-	return elementStores{v}
+	return elementStores{v, new(sync.RWMutex)}
 }
 
 func (e elementStores) Get(storeid string) (*ElementStore, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
 	res, ok := e.stores[storeid]
 	return res, ok
 }
 
 func (e elementStores) Set(store *ElementStore) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	_, ok := e.stores[store.Global.ID]
 	if ok {
 		log.Print("ElementStore already exists")
@@ -66,7 +72,15 @@ type ElementStore struct {
 
 	PersistentStorer map[string]storageFunctions
 
+	Schemas          map[string]Schema // property schemas indexed by constructor name
+	StrictValidation bool              // if true, schema violations panic instead of being reported
+
+	PatternMutationHandlers *MutationCallbacks // pattern handlers observing the mutations of every Element of the store
+	Scheduler               *Scheduler         // if not nil, MutationEvents are queued until flushed
+
 	Global *Element // the global Element stores the global state shared by all *Elements
+
+	propagation *propagation // tracks the mutations of the Elements of the store
 }
 
 type storageFunctions struct {
@@ -81,6 +95,8 @@ type storageFunctions struct {
 type ConstructorOption struct {
 	Name         string
 	Configurator func(*Element) *Element
+
+	schema *Schema // set by WithSchema
 }
 
 func NewConstructorOption(name string, configuratorFn func(*Element) *Element) ConstructorOption {
@@ -105,13 +121,13 @@ func NewConstructorOption(name string, configuratorFn func(*Element) *Element) C
 
 		return configuratorFn(e)
 	}
-	return ConstructorOption{name, fn}
+	return ConstructorOption{Name: name, Configurator: fn}
 }
 
 // NewElementStore creates a new namespace for a list of Element constructors.
 func NewElementStore(storeid string, doctype string) *ElementStore {
 	global := NewElement("global", storeid, doctype)
-	es := &ElementStore{doctype, make(map[string]func(name string, id string, optionNames ...string) *Element, 0), make(map[string]func(*Element) *Element), make(map[string]map[string]func(*Element) *Element, 0), make(map[string]*Element), make(map[string]storageFunctions, 5), global}
+	es := &ElementStore{doctype, make(map[string]func(name string, id string, optionNames ...string) *Element, 0), make(map[string]func(*Element) *Element), make(map[string]map[string]func(*Element) *Element, 0), make(map[string]*Element), make(map[string]storageFunctions, 5), make(map[string]Schema), false, NewMutationCallbacks(), nil, global, &propagation{}}
 	Stores.Set(es)
 	return es
 }
@@ -151,11 +167,18 @@ func (e *ElementStore) NewAppRoot(id string) *Element {
 }
 
 // NewConstructor registers and returns a new Element construcor function.
+// The Schema of the Elements it creates, if any, is passed as an option
+// created by WithSchema.
 func (e *ElementStore) NewConstructor(elementname string, constructor func(name string, id string) *Element, options ...ConstructorOption) func(elname string, elid string, optionNames ...string) *Element {
 	options = append(options, allowPropertyInheritanceOnMount)
+	delete(e.Schemas, elementname)
 	// First we register the options that are passed with the Constructor definition
 	if options != nil {
 		for _, option := range options {
+			if option.schema != nil {
+				e.Schemas[elementname] = *option.schema
+				continue
+			}
 			n := option.Name
 			f := option.Configurator
 			optlist, ok := e.ConstructorsOptions[elementname]
@@ -189,6 +212,8 @@ func (e *ElementStore) NewConstructor(elementname string, constructor func(name
 			}
 		}
 
+		element.validateProperties()
+
 		e.ByID[id] = element
 		return element
 	}
@@ -235,6 +260,11 @@ type Element struct {
 	InactiveViews map[string]View
 
 	Native NativeElement
+
+	computed      map[string]*computation // computed properties indexed by category/propname
+	subscriptions map[*Subscription]struct{}
+	interceptors  map[string][]*interceptor // indexed by category/propname
+	contexts      map[string]*Subscription  // consumed context keys and the Subscription to their provider
 }
 
 func (e *Element) Element() *Element   { return e }
@@ -263,6 +293,10 @@ func NewElement(name string, id string, doctype string) *Element {
 		newViewAccessNode(nil,""),
 		nil,
 		nil,
+		nil,
+		nil,
+		nil,
+		nil,
 	}
 	e.Watch("ui", "command", e, DefaultCommandHandler)
 	return e
@@ -291,6 +325,18 @@ func (e *Element) isRoot() bool {
 	return e == e.Parent
 }
 
+// MutationDeduplication returns whether setting a property to a value equal to
+// its current one skips the dispatch of MutationEvents for the Element.
+// It is enabled via the ("internals","mutationdeduplication") property.
+func MutationDeduplication(e *Element) bool {
+	v, ok := e.Get("internals", "mutationdeduplication")
+	if !ok {
+		return false
+	}
+	b, ok := v.(Bool)
+	return ok && bool(b)
+}
+
 func PersistenceMode(e *Element) string {
 	mode := ""
 	v, ok := e.Get("internals", "persistence")
@@ -399,6 +445,8 @@ func (e *Element) DispatchEvent(evt Event, nativebinding NativeDispatch) *Elemen
 		return e
 	}
 
+	defer e.enterEvent(evt)()
+
 	// First we apply the capturing event handlers PHASE 1
 	evt.SetPhase(1)
 	var done bool
@@ -443,20 +491,26 @@ func (e *Element) DispatchEvent(evt Event, nativebinding NativeDispatch) *Elemen
 // the Element can not be rendered as part of the view.
 func attach(parent *Element, child *Element, activeview bool) {
 	defer func() {
-		child.Set("event", "attached", Bool(true))
-		if child.Mounted() {
+		if v, _ := child.Get("event", "attached"); !isTrue(v) {
+			child.Set("event", "attached", Bool(true))
+		}
+		if v, _ := child.Get("event", "mounted"); !isTrue(v) && child.Mounted() {
 			child.Set("event", "mounted", Bool(true))
 		}
 	}()
 
 	if activeview {
 		child.Parent = parent
-		child.path.InsertFirst(parent).InsertFirst(parent.path.List...)
+		// the path of an Element is the list of its ancestors, starting from the top-most one
+		path := make([]*Element, 0, len(parent.path.List)+1)
+		child.path.List = append(append(path, parent.path.List...), parent)
 	}
 	child.root = parent.root // mounted once means attached for ever unless attached to a new app *root (imagining several apps can be ran concurrently and can share ui elements)
 	child.subtreeRoot = parent.subtreeRoot
 
 	child.link(parent)
+	inherit(parent, child)
+	child.resolveContexts()
 	//child.ViewAccessPath = computePath(child.ViewAccessPath,child.ViewAccessNode)
 
 	for _, descendant := range child.Children.List {
@@ -478,6 +532,11 @@ func detach(e *Element) {
 		return
 	}
 
+	mounted := e.Mounted()
+	if mounted {
+		beforeUnmount(e)
+	}
+
 	e.subtreeRoot = e
 
 	// reset e.path to start with the top-most element i.e. "e" in the current case
@@ -493,13 +552,13 @@ func detach(e *Element) {
 	}
 
 	e.Parent = nil
+	e.resolveContexts()
 
 	// ViewAccessPath handling:
 	e.ViewAccessNode.previous = nil
 	//e.ViewAccessPath = computePath(newViewNodes(), e.ViewAccessNode)
 
 	e.Set("event", "attached", Bool(false))
-	e.Set("event", "mounted", Bool(false))
 
 	// got to update the subtree with the new subtree root and path
 	for _, descendant := range e.Children.List {
@@ -511,6 +570,10 @@ func detach(e *Element) {
 			attach(e, descendant, false)
 		}
 	}
+
+	if mounted {
+		unmount(e)
+	}
 }
 
 // AppendChild appends a new element to the Element's children.
@@ -673,7 +736,9 @@ func (e *Element) removeChild(childEl AnyElement) *Element {
 }
 
 func (e *Element) removeChildren() *Element {
-	for _, child := range e.Children.List {
+	children := make([]*Element, len(e.Children.List))
+	copy(children, e.Children.List)
+	for _, child := range children {
 		e.removeChild(child)
 	}
 	return e
@@ -691,54 +756,102 @@ func (e *Element) hasChild(any AnyElement) (int, bool) {
 	return -1, false
 }
 
-func (e *Element) Watch(category string, propname string, owner *Element, h *MutationHandler) *Element {
+// Watch registers a MutationHandler which is called each time the named property
+// of the owner Element is set.
+// The handler is stored on the owner since it is the Element dispatching the
+// MutationEvents. It remains registered until the returned Subscription is
+// cancelled.
+func (e *Element) Watch(category string, propname string, owner *Element, h *MutationHandler) *Subscription {
 	p, ok := owner.Properties.Categories[category]
 	if !ok {
 		p = newProperties()
 		owner.Properties.Categories[category] = p
 	}
 	p.NewWatcher(propname, e)
-	e.PropMutationHandlers.Add(owner.ID+"/"+category+"/"+propname, h)
-	return e
+	key := owner.ID + "/" + category + "/" + propname
+	owner.PropMutationHandlers.Add(key, h)
+	return newSubscription(e, owner, key, h, func() {
+		if p, ok := owner.Properties.Categories[category]; ok {
+			p.RemoveWatcher(propname, e)
+		}
+		owner.PropMutationHandlers.Remove(key, h)
+	})
 }
 
+// Unwatch cancels every Subscription the Element holds on the named property
+// of the owner.
 func (e *Element) Unwatch(category string, propname string, owner *Element) *Element {
-	p, ok := owner.Properties.Categories[category]
-	if !ok {
-		return e
-	}
-	p.RemoveWatcher(propname, e)
+	key := owner.ID + "/" + category + "/" + propname
+	e.cancelSubscriptions(func(s *Subscription) bool {
+		return s.subscriber == e && s.target == owner && s.key == key
+	})
 	return e
 }
 
-func (e *Element) WatchGroup(category string, target *Element, h *MutationHandler) *Element {
-	p, ok := target.Properties.Categories[category]
-	if !ok {
-		p = newProperties()
-		target.Properties.Categories[category] = p
-	}
-	p.NewWatcher("existifallpropertieswatched", e)
-	e.PropMutationHandlers.Add(target.ID+"/"+category+"/"+"existifallpropertieswatched", h)
-	return e
+// WatchGroup registers a MutationHandler which is called each time any property
+// of the given category of the target Element is set.
+func (e *Element) WatchGroup(category string, target *Element, h *MutationHandler) *Subscription {
+	return e.Watch(category, "existifallpropertieswatched", target, h)
 }
 
 func (e *Element) UnwatchGroup(category string, owner *Element) *Element {
-	p, ok := owner.Properties.Categories[category]
-	if !ok {
-		return e
-	}
-	p.RemoveWatcher("existifallpropertieswatched", e)
-	return e
-}
-
-func (e *Element) AddEventListener(event string, handler *EventHandler, nativebinding NativeEventBridge) *Element {
+	return e.Unwatch(category, "existifallpropertieswatched", owner)
+}
+
+// WatchPattern registers a MutationHandler which is called for each mutation of
+// a property of the Element or of any of its descendants, provided that the
+// observed key of the mutation matches the pattern. See MatchKey.
+// For instance, "*/data/selected" allows a list to observe the selection state
+// of all its items.
+// It returns an ErrInvalidPattern error if the pattern is malformed.
+func (e *Element) WatchPattern(pattern string, h *MutationHandler) (*Subscription, error) {
+	if err := validatePattern(pattern); err != nil {
+		return nil, err
+	}
+	e.PropMutationHandlers.AddPattern(pattern, h)
+	return newSubscription(e, e, pattern, h, func() {
+		e.PropMutationHandlers.RemovePattern(pattern, h)
+	}), nil
+}
+
+// WatchPattern registers a MutationHandler on behalf of the subscriber, which is
+// called for each mutation of a property of any Element of the ElementStore
+// whose observed key matches the pattern. See MatchKey.
+// For instance, "*/ui/*" allows to observe every UI mutation.
+// It returns an ErrInvalidPattern error if the pattern is malformed.
+func (e *ElementStore) WatchPattern(pattern string, subscriber *Element, h *MutationHandler) (*Subscription, error) {
+	if err := validatePattern(pattern); err != nil {
+		return nil, err
+	}
+	e.PatternMutationHandlers.AddPattern(pattern, h)
+	return newSubscription(subscriber, e.Global, pattern, h, func() {
+		e.PatternMutationHandlers.RemovePattern(pattern, h)
+	}), nil
+}
+
+// AddEventListener registers an EventHandler for the named event. If a
+// NativeEventBridge is provided, the event is also listened to on the native
+// element. Cancelling the returned Subscription removes the handler, and the
+// native listener once no handler is left for the event.
+func (e *Element) AddEventListener(event string, handler *EventHandler, nativebinding NativeEventBridge) *Subscription {
 	e.EventHandlers.AddEventHandler(event, handler)
 	if nativebinding != nil {
 		nativebinding(event, e)
 	}
-	return e
+	s := newSubscription(e, e, event, handler, func() {
+		e.EventHandlers.RemoveEventHandler(event, handler)
+		if nativebinding != nil && !e.EventHandlers.hasHandlers(event) {
+			e.NativeEventUnlisteners.Apply(event)
+		}
+	})
+	s.bridge = nativebinding
+	return s
 }
+
 func (e *Element) RemoveEventListener(event string, handler *EventHandler, native bool) *Element {
+	e.cancelSubscriptions(func(s *Subscription) bool {
+		return s.target == e && s.key == event && s.handler == handler
+	})
 	e.EventHandlers.RemoveEventHandler(event, handler)
 	if native {
 		if e.NativeEventUnlisteners.List != nil {
@@ -751,29 +864,30 @@ func (e *Element) RemoveEventListener(event string, handler *EventHandler, nativ
 // Mounted returns whether the subtree the current Element belongs to is attached
 // to the main tree or not.
 func (e *Element) Mounted() bool {
-	if e.Root() == nil {
+	root := e.Root()
+	if root == nil {
 		return false
 	}
-	_, isroot := e.Root().Get("internals", "root")
-	return isroot
-}
-
-func (e *Element) OnMount(h *MutationHandler) {
-	nh := NewMutationHandler(func(evt MutationEvent) bool {
-		b, ok := evt.NewValue().(Bool)
-		if !ok || !bool(b) {
-			return false
+	if _, isroot := root.Get("internals", "root"); !isroot {
+		return false
+	}
+	for a := e; a != nil; a = a.Parent {
+		if a == root {
+			return true
 		}
-		return h.Handle(evt)
-	})
-	e.Watch("event", "mounted", e, nh)
+	}
+	return false
 }
 
 // Get retrieves the value stored for the named property located under the given
 // category. The "" category returns the content of the "global" property category.
 // The "global" namespace is a local copy of the data that resides in the global
 // shared scope common to all Element objects of an ElementStore.
+// A stale computed property is recomputed before being returned.
 func (e *Element) Get(category, propname string) (Value, bool) {
+	if c, ok := e.computed[category+"/"+propname]; ok && c.stale {
+		c.run()
+	}
 	return e.Properties.Get(category, propname)
 }
 
@@ -781,11 +895,58 @@ func (e *Element) Get(category, propname string) (Value, bool) {
 // First flag in the variadic argument, if true, denotes whether the property should be inheritable.
 // The "ui" category is unformally reserved for properties that are a UI representation
 // of data.
-func (e *Element) Set(category string, propname string, value Value, flags ...bool) {
+// If mutation deduplication is enabled for the Element, setting a value that is
+// structurally equal to the current one does not dispatch any MutationEvent.
+// If the Element was created by a constructor which has a registered Schema,
+// a value which does not conform to it is rejected and an error is returned.
+// If the ElementStore has a Scheduler, the MutationEvent is queued until the
+// next flush instead of being dispatched right away.
+// The value is passed through the interceptors registered for the property
+// beforehand. If one of them vetoes the change, its error is returned.
+func (e *Element) Set(category string, propname string, value Value, flags ...bool) error {
+	return e.set(category, propname, value, dispatchNow(e.dispatchMutation), flags...)
+}
+
+// dispatcher receives the MutationEvent resulting from a property change.
+// inheritable reports whether the change affects the values inherited by the
+// descendants of the Element, which have to be updated once the event has been
+// dispatched.
+type dispatcher func(evt MutationEvent, inheritable bool)
+
+// dispatchNow returns a dispatcher which dispatches MutationEvents via
+// dispatch and propagates inheritable changes right after.
+func dispatchNow(dispatch func(MutationEvent)) dispatcher {
+	return func(evt MutationEvent, inheritable bool) {
+		dispatch(evt)
+		if inheritable {
+			evt.Origin().propagateToDescendants(evt)
+		}
+	}
+}
+
+// set implements Set. The MutationEvents resulting from the change are passed
+// to the dispatcher, which allows for their dispatch to be deferred.
+func (e *Element) set(category string, propname string, value Value, dispatch dispatcher, flags ...bool) error {
+	value, err := e.intercept(category, propname, value)
+	if err != nil {
+		return err
+	}
+	return e.store(category, propname, value, dispatch, flags...)
+}
+
+// store stores a property value that has already been intercepted, and
+// dispatches the corresponding MutationEvent.
+func (e *Element) store(category string, propname string, value Value, dispatch dispatcher, flags ...bool) error {
+	if err := e.validate(category, propname, value); err != nil {
+		return err
+	}
+	p := e.propagation()
+	mark := p.errorMark()
 	var inheritable bool
 	if len(flags) > 0 {
 		inheritable = flags[0]
 	}
+	unchanged := e.isUnchanged(category, propname, value)
 	// Persist property if persistence mode has been set at Element creation
 	pmode := PersistenceMode(e)
 
@@ -798,7 +959,7 @@ func (e *Element) Set(category string, propname string, value Value, flags ...bo
 		}
 	}
 
-	if category == "ui" && propname != "mutationrecords" && propname != "command" {
+	if category == "ui" && propname != "mutationrecords" && propname != "command" && !unchanged {
 		mrs, ok := e.Get("ui", "mutationrecords")
 		if !ok {
 			mrs = NewList()
@@ -808,7 +969,7 @@ func (e *Element) Set(category string, propname string, value Value, flags ...bo
 			mrslist = NewList()
 		}
 		mrslist = append(mrslist, NewMutationRecord(category, propname, value))
-		e.Set("ui", "mutationrecords", mrslist)
+		e.set("ui", "mutationrecords", mrslist, dispatch)
 	}
 
 	// Mutationrecords persistence
@@ -818,9 +979,23 @@ func (e *Element) Set(category string, propname string, value Value, flags ...bo
 			storage.Store(e, category, propname, value, flags...)
 		}
 	}
+	old, existed := e.Properties.Get(category, propname)
 	e.Properties.Set(category, propname, value, inheritable)
-	evt := e.NewMutationEvent(category, propname, value)
-	e.PropMutationHandlers.DispatchEvent(evt)
+	if unchanged {
+		return nil
+	}
+	dispatch(e.NewMutationEvent(category, propname, value).WithOldValue(old, existed), inheritable)
+	return p.propagationError(mark)
+}
+
+// isUnchanged returns whether setting the property to the given value can be
+// skipped as far as mutation dispatch is concerned.
+func (e *Element) isUnchanged(category string, propname string, value Value) bool {
+	if !MutationDeduplication(e) {
+		return false
+	}
+	old, ok := e.Get(category, propname)
+	return ok && Equal(old, value)
 }
 
 func (e *Element) GetData(propname string) (Value, bool) {
@@ -831,26 +1006,36 @@ func (e *Element) GetData(propname string) (Value, bool) {
 // First flag in the variadic argument, if true, denotes whether the property should be inheritable.
 // It does not automatically update any potential property representation stored
 // for rendering use in the "ui" category/namespace.
-func (e *Element) SetData(propname string, value Value, flags ...bool) {
-	e.Set("data", propname, value, flags...)
+func (e *Element) SetData(propname string, value Value, flags ...bool) error {
+	return e.Set("data", propname, value, flags...)
 }
 
 // SetUI stores data used for Graphical rendering in the "ui" namespace (stands for
 // user interface). This namespace should remain private to an Element.
 // Other Element may want to "watch" the corresponding data namespace instead if
 // there exist inter-dependences.
-func (e *Element) SetUI(propname string, value Value, flags ...bool) {
-	e.Set("ui", propname, value, flags...)
+func (e *Element) SetUI(propname string, value Value, flags ...bool) error {
+	return e.Set("ui", propname, value, flags...)
 }
 
 // SetDataSyncUI will set a "data" property and update the same-name property value
 // located in the "ui namespace/category and used by the User Interface, for instance, for rendering..
 // Typically NOT used when the data is being updated from the UI.
-func (e *Element) SetDataSyncUI(propname string, value Value, flags ...bool) {
+func (e *Element) SetDataSyncUI(propname string, value Value, flags ...bool) error {
+	value, err := e.intercept("data", propname, value)
+	if err != nil {
+		return err
+	}
+	if err := e.validate("data", propname, value); err != nil {
+		return err
+	}
+	p := e.propagation()
+	mark := p.errorMark()
 	var inheritable bool
 	if len(flags) > 0 {
 		inheritable = flags[0]
 	}
+	unchanged := e.isUnchanged("data", propname, value)
 	// Persist property if persistence mode has been set at Element creation
 	pmode := PersistenceMode(e)
 
@@ -860,12 +1045,19 @@ func (e *Element) SetDataSyncUI(propname string, value Value, flags ...bool) {
 			storage.Store(e, "data", propname, value, flags...)
 		}
 	}
+	old, existed := e.Properties.Get("data", propname)
 	e.Properties.Set("data", propname, value, inheritable)
 
-	e.Set("ui", propname, value, flags...)
+	err = e.Set("ui", propname, value, flags...)
 
-	evt := e.NewMutationEvent("data", propname, value)
-	e.PropMutationHandlers.DispatchEvent(evt)
+	if unchanged {
+		return err
+	}
+	dispatchNow(e.dispatchMutation)(e.NewMutationEvent("data", propname, value).WithOldValue(old, existed), inheritable)
+	if err != nil {
+		return err
+	}
+	return p.propagationError(mark)
 }
 
 // SyncUISetData is used in event handlers when a user changed a value accessible
@@ -877,7 +1069,15 @@ func (e *Element) SetDataSyncUI(propname string, value Value, flags ...bool) {
 // with a call to SetData (and not SetDataSyncUI since the UI value is alread up-to-date).
 //
 // First flag in the variadic argument, if true, denotes whether the property should be inheritable.
-func (e *Element) SyncUISetData(propname string, value Value, flags ...bool) {
+func (e *Element) SyncUISetData(propname string, value Value, flags ...bool) error {
+	defer e.enterMutationSource(SourceUserInput)()
+	value, err := e.intercept("ui", propname, value)
+	if err != nil {
+		return err
+	}
+	if err := e.validate("ui", propname, value); err != nil {
+		return err
+	}
 	var inheritable bool
 	if len(flags) > 0 {
 		inheritable = flags[0]
@@ -907,7 +1107,7 @@ func (e *Element) SyncUISetData(propname string, value Value, flags ...bool) {
 		e.Set("ui", "mutationrecords", mrslist)
 	}
 
-	e.SetData(propname, value, flags...)
+	return e.SetData(propname, value, flags...)
 }
 
 // LoadProperty is a function typically used to return a UI Element to a
@@ -917,20 +1117,40 @@ func (e *Element) SyncUISetData(propname string, value Value, flags ...bool) {
 // we create and dispatch a mutation event since loading a property is change inducing at the
 // UI level.
 func LoadProperty(e *Element, category string, propname string, proptype string, value Value) {
+	defer e.enterMutationSource(SourceLoad)()
+	old, existed := e.Properties.Get(category, propname)
 	e.Properties.Load(category, propname, proptype, value)
 	if category == "ui" {
-		evt := e.NewMutationEvent(category, propname, value)
-		e.PropMutationHandlers.DispatchEvent(evt)
+		evt := e.NewMutationEvent(category, propname, value).WithOldValue(old, existed)
+		e.dispatchMutation(evt)
 	}
 }
 
 // Delete removes the property stored for the given category if it exists.
+// Once an inheritable property is deleted, the descendants inherit the value
+// provided by the nearest ancestor instead, if any.
 // Inherited properties cannot be deleted.
 // Default properties cannot be deleted either for now.
-func (e *Element) Delete(category string, propname string) {
+// The MutationEvent dispatched holds Null as new value.
+// Properties marked as required in the Element Schema cannot be deleted.
+func (e *Element) Delete(category string, propname string) error {
+	return e.delete(category, propname, dispatchNow(e.dispatchMutation))
+}
+
+func (e *Element) delete(category string, propname string, dispatch dispatcher) error {
+	if err := e.validateDeletion(category, propname); err != nil {
+		return err
+	}
+	p := e.propagation()
+	mark := p.errorMark()
+	old, existed := e.Properties.Get(category, propname)
+	var inheritable bool
+	if ps, ok := e.Properties.Categories[category]; ok {
+		_, inheritable = ps.Inheritable[propname]
+	}
 	e.Properties.Delete(category, propname)
-	evt := e.NewMutationEvent(category, propname, nil)
-	e.PropMutationHandlers.DispatchEvent(evt)
+	dispatch(e.NewMutationEvent(category, propname, Null).WithOldValue(old, existed), inheritable)
+	return p.propagationError(mark)
 }
 
 func SetDefault(e *Element, category string, propname string, value Value) {
@@ -963,22 +1183,33 @@ func InheritProperties(target *Element, src *Element, categories ...string) {
 }
 
 var allowPropertyInheritanceOnMount = NewConstructorOption("propertyinheritance", func(e *Element) *Element {
-	h := NewMutationHandler(func(evt MutationEvent) bool {
-		element := evt.Origin()
-		InheritProperties(element, element.Parent)
-		return false
-	})
-	e.OnMount(h)
+	e.Set("internals", "propertyinheritance", Bool(true))
 	return e
 })
 
 // EnablePropertyAutoInheritance is an option that when passed to an Element
-// constructor, allows an Element to inherit the properties of its parent
-// when it is mounted in the DOM tree.
+// constructor, allows an Element to inherit the inheritable properties of its
+// ancestors. Inherited values are updated live when the ancestors change them.
 func EnablePropertyAutoInheritance() string {
 	return "propertyinheritance"
 }
 
+// AllowMutationDeduplication is a constructor option which prevents an Element
+// from dispatching MutationEvents when a property is set to a value that is
+// structurally equal to the current one.
+var AllowMutationDeduplication = NewConstructorOption("mutationdeduplication", func(e *Element) *Element {
+	e.Set("internals", "mutationdeduplication", Bool(true))
+	return e
+})
+
+// EnableMutationDeduplication is an option that when passed to an Element
+// constructor, enables mutation deduplication for the constructed Element.
+// AllowMutationDeduplication needs to have been registered with the constructor
+// or as a global option of the ElementStore.
+func EnableMutationDeduplication() string {
+	return "mutationdeduplication"
+}
+
 // Route returns the path to an Element.
 // If the path to an Element includes a parameterized view, the returned route is
 // parameterized as well.
@@ -1306,6 +1537,7 @@ func (p Properties) Set(propName string, value Value, inheritable bool) {
 
 func (p Properties) Delete(propname string) {
 	delete(p.Local, propname)
+	delete(p.Inheritable, propname)
 }
 
 func (p Properties) Inherit(source Properties) {
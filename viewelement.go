@@ -178,7 +178,7 @@ func (e *Element) activateView(name string) error {
 				cccl := make([]*Element, len(e.Children.List))
 				copy(cccl, e.Children.List)
 				if ok && ok2 && oldviewname != "" && e.Children != nil {
-					for _, child := range e.Children.List {
+					for _, child := range cccl {
 						if !viewIsParameterized {
 							e.removeChild(child)
 							attach(e, child, false)
@@ -188,6 +188,7 @@ func (e *Element) activateView(name string) error {
 						// the view is not parameterized
 						e.InactiveViews[string(oldviewname)] = NewView(string(oldviewname), cccl...)
 					}
+					e.Set("event", "viewdeactivated", oldviewname)
 				}
 				e.ActiveView = parameterName
 				// Let's append the new view Elements
@@ -195,6 +196,7 @@ func (e *Element) activateView(name string) error {
 					e.appendChild(newchild)
 				}
 				e.SetDataSyncUI("activeview", String(name), false)
+				e.Set("event", "viewactivated", String(name))
 
 				return nil
 			}
@@ -209,7 +211,7 @@ func (e *Element) activateView(name string) error {
 	cccl := make([]*Element, len(e.Children.List))
 	copy(cccl, e.Children.List)
 	if ok && ok2 && e.Children != nil {
-		for _, child := range e.Children.List {
+		for _, child := range cccl {
 			if !viewIsParameterized {
 				e.removeChild(child)
 				attach(e, child, false)
@@ -219,6 +221,7 @@ func (e *Element) activateView(name string) error {
 			// the view is not parameterized, we put it back in the set of activable views
 			e.InactiveViews[string(oldviewname)] = NewView(string(oldviewname), cccl...)
 		}
+		e.Set("event", "viewdeactivated", oldviewname)
 	}
 	e.ActiveView = name
 	// we attach and activate the desired view
@@ -227,6 +230,7 @@ func (e *Element) activateView(name string) error {
 	}
 	delete(e.InactiveViews, name)
 	e.Set("ui", "activeview", String(name), false)
+	e.Set("event", "viewactivated", String(name))
 
 	return nil
 }